@@ -116,6 +116,20 @@ CREATE TABLE IF NOT EXISTS `MATERIAL_EVENT` (
 );
 
 CREATE INDEX `MATERIAL_EVENT_MATERIAL_UID_INDEX` ON `MATERIAL_EVENT` (`MATERIAL_UID`);
+CREATE UNIQUE INDEX `MATERIAL_EVENT_MATERIAL_UID_VERSION_UNIQUE_INDEX` ON `MATERIAL_EVENT` (`MATERIAL_UID`, `VERSION`);
+
+-- The latest snapshot of a material, written every MaterialSnapshotInterval events,
+-- so loading a material only replays the events stored after it.
+CREATE TABLE IF NOT EXISTS `MATERIAL_SNAPSHOT` (
+    `MATERIAL_UID` BINARY(16) PRIMARY KEY,
+    `VERSION` INT,
+    `SNAPSHOT` JSON
+);
+
+CREATE TABLE IF NOT EXISTS `MATERIAL_EXTERNAL_ID` (
+    `EXTERNAL_ID` VARCHAR(255) PRIMARY KEY,
+    `MATERIAL_UID` BINARY(16)
+);
 
 CREATE TABLE IF NOT EXISTS `MATERIAL_READ` (
     `UID` BINARY(16) PRIMARY KEY,
@@ -129,11 +143,18 @@ CREATE TABLE IF NOT EXISTS `MATERIAL_READ` (
     `EXPIRATION_DATE` VARCHAR(255),
     `NOTES` VARCHAR(255),
     `PRODUCED_BY` VARCHAR(255),
-    `CREATED_DATE` DATETIME
+    `IS_EXPENSE` TINYINT(1),
+    `CREATED_DATE` DATETIME,
+    `IS_ARCHIVED` TINYINT(1) DEFAULT 0
 );
 
 CREATE INDEX `MATERIAL_READ_UID_UNIQUE_INDEX` ON `MATERIAL_READ` (`UID`);
 
+-- Adds the columns MATERIAL_READ tables created by an older DDL don't have yet.
+-- On an up to date table they fail with a duplicate column error, which is skipped.
+ALTER TABLE `MATERIAL_READ` ADD COLUMN `IS_ARCHIVED` TINYINT(1) DEFAULT 0;
+ALTER TABLE `MATERIAL_READ` ADD COLUMN `IS_EXPENSE` TINYINT(1);
+
 -- CROP --
 
 CREATE TABLE IF NOT EXISTS `CROP_EVENT` (
@@ -116,6 +116,20 @@ CREATE TABLE IF NOT EXISTS "MATERIAL_EVENT" (
 );
 
 CREATE INDEX IF NOT EXISTS "MATERIAL_EVENT_MATERIAL_UID_INDEX" ON "MATERIAL_EVENT" ("MATERIAL_UID");
+CREATE UNIQUE INDEX IF NOT EXISTS "MATERIAL_EVENT_MATERIAL_UID_VERSION_UNIQUE_INDEX" ON "MATERIAL_EVENT" ("MATERIAL_UID", "VERSION");
+
+-- The latest snapshot of a material, written every MaterialSnapshotInterval events,
+-- so loading a material only replays the events stored after it.
+CREATE TABLE IF NOT EXISTS "MATERIAL_SNAPSHOT" (
+    "MATERIAL_UID" BLOB PRIMARY KEY,
+    "VERSION" INTEGER,
+    "SNAPSHOT" BLOB
+);
+
+CREATE TABLE IF NOT EXISTS "MATERIAL_EXTERNAL_ID" (
+    "EXTERNAL_ID" TEXT PRIMARY KEY,
+    "MATERIAL_UID" BLOB
+);
 
 CREATE TABLE IF NOT EXISTS "MATERIAL_READ" (
     "UID" BLOB PRIMARY KEY,
@@ -129,11 +143,18 @@ CREATE TABLE IF NOT EXISTS "MATERIAL_READ" (
     "EXPIRATION_DATE" TEXT,
     "NOTES" TEXT,
     "PRODUCED_BY" TEXT,
-    "CREATED_DATE" TEXT
+    "IS_EXPENSE" BOOLEAN,
+    "CREATED_DATE" TEXT,
+    "IS_ARCHIVED" BOOLEAN DEFAULT 0
 );
 
 CREATE INDEX IF NOT EXISTS "MATERIAL_READ_UID_UNIQUE_INDEX" ON "MATERIAL_READ" ("UID");
 
+-- Adds the columns MATERIAL_READ tables created by an older DDL don't have yet.
+-- On an up to date table they fail with a duplicate column error, which is skipped.
+ALTER TABLE "MATERIAL_READ" ADD COLUMN "IS_ARCHIVED" BOOLEAN DEFAULT 0;
+ALTER TABLE "MATERIAL_READ" ADD COLUMN "IS_EXPENSE" BOOLEAN;
+
 -- CROP --
 
 CREATE TABLE IF NOT EXISTS "CROP_EVENT" (
@@ -339,8 +339,9 @@ func initMysql() *sql.DB {
 				// http://dev.mysql.com/doc/refman/5.7/en/error-messages-server.html
 				// We will skip error duplicate key name in database (code: 1061),
 				// because CREATE INDEX doesn't have IF NOT EXISTS clause,
+				// and error duplicate column name (code: 1060), because neither has ADD COLUMN,
 				// otherwise we will stop the loop and print the error
-				if me.Number == 1061 {
+				if me.Number == 1061 || me.Number == 1060 {
 
 				} else {
 					log.Print(err)
@@ -373,11 +374,20 @@ func initSqlite() *sql.DB {
 	if err != nil {
 		panic(err)
 	}
-	sql := string(ddl)
+	sqls := string(ddl)
 
-	_, err = db.Exec(sql)
-	if err != nil {
-		panic(err)
+	// The DDL is executed statement by statement like the MySQL one,
+	// so an ALTER TABLE ADD COLUMN that already ran can be skipped.
+	// SQLite doesn't have an IF NOT EXISTS clause for ADD COLUMN.
+	for _, v := range strings.Split(sqls, ";") {
+		if len(strings.TrimSpace(v)) == 0 {
+			continue
+		}
+
+		_, err = db.Exec(v)
+		if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			panic(err)
+		}
 	}
 
 	log.Print("DDL file executed")
@@ -115,8 +115,9 @@ func MaterialTypeHook() mapstructure.DecodeHookFunc {
 				mapped2 := mapped["Data"].(map[string]interface{})
 				mapped3 := mapped2["PlantType"].(map[string]interface{})
 				typeCode := mapped3["code"].(string)
+				packaging, _ := mapped2["Packaging"].(string)
 
-				t, err := domain.CreateMaterialTypePlant(typeCode)
+				t, err := domain.CreateMaterialTypePlantWithPackaging(typeCode, packaging)
 				if err != nil {
 					return data, err
 				}
@@ -71,6 +71,76 @@ func (w *MaterialEventWrapper) UnmarshalJSON(b []byte) error {
 
 		w.EventData = e
 
+	case "MaterialQuantityConsumed":
+		e := domain.MaterialQuantityConsumed{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialQuantityRestocked":
+		e := domain.MaterialQuantityRestocked{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialQuantityReserved":
+		e := domain.MaterialQuantityReserved{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialReservationReleased":
+		e := domain.MaterialReservationReleased{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialLotAdded":
+		e := domain.MaterialLotAdded{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialLotConsumed":
+		e := domain.MaterialLotConsumed{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialLowStockThresholdChanged":
+		e := domain.MaterialLowStockThresholdChanged{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
 	case "MaterialTypeChanged":
 		e := domain.MaterialTypeChanged{}
 
@@ -81,6 +151,16 @@ func (w *MaterialEventWrapper) UnmarshalJSON(b []byte) error {
 
 		w.EventData = e
 
+	case "MaterialPlantTypeChanged":
+		e := domain.MaterialPlantTypeChanged{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
 	case "MaterialExpirationDateChanged":
 		e := domain.MaterialExpirationDateChanged{}
 
@@ -109,6 +189,146 @@ func (w *MaterialEventWrapper) UnmarshalJSON(b []byte) error {
 			return err
 		}
 
+		w.EventData = e
+
+	case "MaterialSupplierChanged":
+		e := domain.MaterialSupplierChanged{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialExpired":
+		e := domain.MaterialExpired{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialMerged":
+		e := domain.MaterialMerged{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialArchived":
+		e := domain.MaterialArchived{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialUnarchived":
+		e := domain.MaterialUnarchived{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialProduced":
+		e := domain.MaterialProduced{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialComponentAdded":
+		e := domain.MaterialComponentAdded{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialComponentRemoved":
+		e := domain.MaterialComponentRemoved{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialAttachmentAdded":
+		e := domain.MaterialAttachmentAdded{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialAttachmentRemoved":
+		e := domain.MaterialAttachmentRemoved{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialTagAdded":
+		e := domain.MaterialTagAdded{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialTagRemoved":
+		e := domain.MaterialTagRemoved{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialSupplierSKUSet":
+		e := domain.MaterialSupplierSKUSet{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
+		w.EventData = e
+
+	case "MaterialSupplierSKURemoved":
+		e := domain.MaterialSupplierSKURemoved{}
+
+		_, err := Decode(f, &mapped, &e)
+		if err != nil {
+			return err
+		}
+
 		w.EventData = e
 	}
 
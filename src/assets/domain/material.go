@@ -1,7 +1,13 @@
 package domain
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	uuid "github.com/satori/go.uuid"
@@ -13,54 +19,472 @@ type Material struct {
 	PricePerUnit   PricePerUnit     `json:"price_per_unit"`
 	Type           MaterialType     `json:"type"`
 	Quantity       MaterialQuantity `json:"quantity"`
-	ExpirationDate *time.Time       `json:"expiration_date"`
-	Notes          *string          `json:"notes"`
-	ProducedBy     *string          `json:"produced_by"`
+	ExpirationDate *time.Time       `json:"expiration_date,omitempty"`
+	Notes          *string          `json:"notes,omitempty"`
+	ProducedBy     *string          `json:"produced_by,omitempty"`
+	IsExpense      *bool            `json:"is_expense,omitempty"`
+	SupplierID     *uuid.UUID       `json:"supplier_id,omitempty"`
+	ExternalID     *string          `json:"external_id,omitempty"`
 	CreatedDate    time.Time        `json:"created_date"`
+	IsExpired      bool             `json:"is_expired"`
+	Archived       bool             `json:"archived"`
+	Tags           []string         `json:"tags"`
+
+	LowStockThreshold *float32 `json:"low_stock_threshold,omitempty"`
+
+	// Reserved is the part of Quantity.Value set aside for planned use
+	Reserved float32 `json:"reserved"`
+
+	// Lots are the batches part of the stock is tracked in. Quantity.Value is the whole
+	// stock, the quantity of every lot plus StockOutsideLots, so lot events change both
+	// the lot and Quantity.Value. Quantity.Value is never less than the lots.
+	Lots []MaterialLot `json:"lots"`
+
+	// Components are the materials this one is mixed from, e.g. for a soil mix
+	Components []MaterialComponent `json:"components"`
+
+	// Attachments are pictures of the material, like its product label
+	Attachments []Attachment `json:"attachments"`
+
+	// SupplierSKUs maps a supplier ID to the catalog number the supplier sells the material under
+	SupplierSKUs map[uuid.UUID]string `json:"supplier_skus,omitempty"`
 
 	// Events
 	Version            int
 	UncommittedChanges []interface{}
+
+	// history is the audit trail of every change applied, committed or not
+	history []ChangeRecord
+
+	// priceHistory is every price the material had, oldest first
+	priceHistory []PricedAt
+}
+
+// PricedAt is a price of the material and the time it became effective
+type PricedAt struct {
+	Price         PricePerUnit `json:"price"`
+	EffectiveFrom time.Time    `json:"effective_from"`
+}
+
+// ChangeRecord is a single audit entry of a material change
+type ChangeRecord struct {
+	Field     string    `json:"field"`
+	Value     string    `json:"value"`
+	ChangedAt time.Time `json:"changed_at"`
+	ChangedBy uuid.UUID `json:"changed_by"`
+}
+
+// MaterialLot is a single purchase batch of a material
+type MaterialLot struct {
+	LotNumber      string     `json:"lot_number"`
+	Quantity       float32    `json:"quantity"`
+	ExpirationDate *time.Time `json:"expiration_date"`
 }
 
 const (
 	MoneyEUR = "EUR"
 	MoneyIDR = "IDR"
+	MoneyUSD = "USD"
+)
+
+const (
+	ProducedByInternal = "INTERNAL"
+	ProducedBySupplier = "SUPPLIER"
 )
 
-type PricePerUnit struct {
+type ProducedBySource struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+}
+
+func ProducedBySources() []ProducedBySource {
+	return []ProducedBySource{
+		{Code: ProducedByInternal, Label: "Internal"},
+		{Code: ProducedBySupplier, Label: "Supplier"},
+	}
+}
+
+func GetProducedBySource(code string) ProducedBySource {
+	for _, v := range ProducedBySources() {
+		if v.Code == code {
+			return v
+		}
+	}
+
+	return ProducedBySource{}
+}
+
+// Money is an amount in a currency, like the value of a whole stock.
+type Money struct {
 	Amount       string `json:"amount"`
 	CurrencyCode string `json:"code"`
 }
 
-func (p PricePerUnit) Symbol() string {
+// PricePerUnit is the Money one quantity unit of a material costs.
+type PricePerUnit = Money
+
+func (p Money) Symbol() string {
 	switch p.CurrencyCode {
 	case MoneyEUR:
 		return "€"
+	case MoneyIDR:
+		return "Rp"
+	case MoneyUSD:
+		return "$"
 	default:
 		return ""
 	}
 }
 
+func (p Money) AmountFloat() (float64, error) {
+	a, err := strconv.ParseFloat(p.Amount, 64)
+	if err != nil {
+		return 0, errors.New("invalid price amount")
+	}
+
+	return a, nil
+}
+
 func CreatePricePerUnit(amount, currencyCode string) (PricePerUnit, error) {
+	err := validatePriceAmount(amount)
+	if err != nil {
+		return PricePerUnit{}, err
+	}
+
 	cc, err := GetCurrencyCode(currencyCode)
 	if err != nil {
 		return PricePerUnit{}, err
 	}
 
+	decimals := amountDecimals(cc)
+	scaled, err := parseScaledAmount(amount, decimals)
+	if err != nil {
+		return PricePerUnit{}, err
+	}
+
 	return PricePerUnit{
-		Amount:       amount,
+		Amount:       formatScaledAmount(scaled, decimals),
 		CurrencyCode: cc,
 	}, nil
 }
 
-func GetCurrencyCode(currencyCode string) (string, error) {
-	switch currencyCode {
-	case MoneyEUR:
-		return MoneyEUR, nil
+// MinorUnits returns the amount in hundredths (e.g. cents), so amounts can be
+// compared and summed without float rounding.
+func (p Money) MinorUnits() (int64, error) {
+	return parseMinorUnits(p.Amount)
+}
+
+// Equals tells whether both prices have the same currency and amount,
+// regardless of how the amount is formatted.
+func (p Money) Equals(other Money) bool {
+	if p.CurrencyCode != other.CurrencyCode {
+		return false
+	}
+
+	a, err := p.MinorUnits()
+	if err != nil {
+		return false
+	}
+
+	b, err := other.MinorUnits()
+	if err != nil {
+		return false
+	}
+
+	return a == b
+}
+
+// AdjustByPercent returns the price raised by percent, or lowered when percent is negative.
+// The new amount is rounded half away from zero to the decimals of its currency.
+func (p Money) AdjustByPercent(percent float64) (Money, error) {
+	minorUnits, err := p.MinorUnits()
+	if err != nil {
+		return Money{}, err
+	}
+
+	adjusted := float64(minorUnits) * (100 + percent) / 100
+	if math.Round(adjusted) < 0 {
+		return Money{}, MaterialError{Code: MaterialErrorInvalidPrice, Field: "price_per_unit"}
+	}
+
+	return Money{
+		Amount:       formatAmount(adjusted, p.CurrencyCode),
+		CurrencyCode: p.CurrencyCode,
+	}, nil
+}
+
+// moneySeparators are the thousands and decimal separators of a locale language.
+var moneySeparators = map[string][2]string{
+	"en": {",", "."},
+	"id": {".", ","},
+}
+
+// Formatted formats the amount for display with the currency symbol, the decimals
+// of the currency and the separators of locale, e.g. "Rp1.000.000" for id-ID or
+// "€1,000.00" for en. Locales without separators of their own are formatted like en.
+func (p Money) Formatted(locale string) string {
+	decimals := amountDecimals(p.CurrencyCode)
+	scaled, err := parseScaledAmount(p.Amount, decimals)
+	if err != nil {
+		return p.Amount + " " + p.CurrencyCode
+	}
+
+	separators, ok := moneySeparators[localeLanguage(locale)]
+	if !ok {
+		separators = moneySeparators["en"]
+	}
+
+	symbol := p.Symbol()
+	if symbol == "" {
+		symbol = p.CurrencyCode + " "
+	}
+
+	if decimals == 0 {
+		return symbol + groupThousands(scaled, separators[0])
+	}
+
+	scale := int64(math.Pow10(decimals))
+
+	return symbol + groupThousands(scaled/scale, separators[0]) + separators[1] + fmt.Sprintf("%0*d", decimals, scaled%scale)
+}
+
+// localeLanguage returns the lowercase language of locale without its region,
+// e.g. "id" for "id-ID" or "id_ID".
+func localeLanguage(locale string) string {
+	language := strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+
+	return language
+}
+
+func groupThousands(n int64, separator string) string {
+	digits := strconv.FormatInt(n, 10)
+
+	grouped := ""
+	for len(digits) > 3 {
+		grouped = separator + digits[len(digits)-3:] + grouped
+		digits = digits[:len(digits)-3]
+	}
+
+	return digits + grouped
+}
+
+// TotalValue is the value of the current stock, its price per unit times Quantity.Value,
+// rounded half away from zero to the decimals of its currency.
+func (m Material) TotalValue() (Money, error) {
+	minorUnits, err := m.PricePerUnit.MinorUnits()
+	if err != nil {
+		return Money{}, err
+	}
+
+	total := float64(minorUnits) * float64(m.Quantity.Value)
+
+	return Money{
+		Amount:       formatAmount(total, m.PricePerUnit.CurrencyCode),
+		CurrencyCode: m.PricePerUnit.CurrencyCode,
+	}, nil
+}
+
+// CreateMoney validates the amount and currency code and rounds the amount
+// to the decimals of the currency, e.g. IDR "1000.50" becomes "1001".
+// The currency code is case insensitive.
+func CreateMoney(amount string, currencyCode CurrencyCode) (Money, error) {
+	cc, err := ParseCurrencyCode(string(currencyCode))
+	if err != nil {
+		return Money{}, err
+	}
+
+	return CreatePricePerUnit(amount, string(cc))
+}
+
+// CreateMoneyFromString is CreateMoney for a currency code that isn't typed yet, e.g. from a form.
+func CreateMoneyFromString(amount, currencyCode string) (Money, error) {
+	return CreateMoney(amount, CurrencyCode(currencyCode))
+}
+
+// parseMinorUnits reads a decimal amount as an integer number of hundredths,
+// rounding half up on the third decimal. Amounts in exponent notation go through float.
+func parseMinorUnits(amount string) (int64, error) {
+	return parseScaledAmount(amount, 2)
+}
+
+// parseScaledAmount reads a decimal amount as an integer number of 10^-decimals units,
+// rounding half up on the first dropped decimal only. Amounts in exponent notation go through float.
+func parseScaledAmount(amount string, decimals int) (int64, error) {
+	amount = strings.TrimSpace(amount)
+	parts := strings.SplitN(amount, ".", 2)
+	scale := math.Pow10(decimals)
+
+	whole, err := strconv.ParseUint(parts[0], 10, 63)
+	if err != nil && parts[0] != "" {
+		a, err := strconv.ParseFloat(amount, 64)
+		if err != nil || math.IsNaN(a) || math.IsInf(a, 0) || a < 0 {
+			return 0, errors.New("price must be a non-negative number")
+		}
+
+		return int64(math.Round(a * scale)), nil
+	}
+
+	frac := ""
+	if len(parts) == 2 {
+		frac = parts[1]
+	}
+	for _, c := range frac {
+		if c < '0' || c > '9' {
+			return 0, errors.New("price must be a non-negative number")
+		}
+	}
+	if parts[0] == "" && frac == "" {
+		return 0, errors.New("price must be a non-negative number")
+	}
+
+	frac += strings.Repeat("0", decimals+1)
+	kept, _ := strconv.ParseInt("0"+frac[:decimals], 10, 64)
+
+	scaled := int64(whole)*int64(scale) + kept
+	if frac[decimals] >= '5' {
+		scaled++
+	}
+
+	return scaled, nil
+}
+
+// formatMinorUnits formats hundredths as an amount with two decimals
+func formatMinorUnits(minorUnits int64) string {
+	return formatScaledAmount(minorUnits, 2)
+}
+
+// formatScaledAmount formats an integer number of 10^-decimals units as an amount with decimals decimals.
+func formatScaledAmount(scaled int64, decimals int) string {
+	if decimals == 0 {
+		return strconv.FormatInt(scaled, 10)
+	}
+
+	scale := int64(math.Pow10(decimals))
+
+	return fmt.Sprintf("%d.%0*d", scaled/scale, decimals, scaled%scale)
+}
+
+// currencyDecimals is how many decimals the amounts of a currency are rounded to.
+// Rupiah has no cents in practice.
+var currencyDecimals = map[string]int{
+	MoneyEUR: 2,
+	MoneyIDR: 0,
+	MoneyUSD: 2,
+}
+
+// amountDecimals is the number of decimals amounts in the currency are rounded to.
+// Unknown currencies keep two decimals.
+func amountDecimals(currencyCode string) int {
+	decimals, ok := currencyDecimals[currencyCode]
+	if !ok {
+		return 2
+	}
+
+	return decimals
+}
+
+// formatAmount rounds an amount in hundredths, e.g. a price times a quantity,
+// once and half away from zero to the decimals of the currency and formats it.
+func formatAmount(minorUnits float64, currencyCode string) string {
+	decimals := amountDecimals(currencyCode)
+
+	scaled := minorUnits / math.Pow10(2-decimals)
+	if decimals > 2 {
+		scaled = minorUnits * math.Pow10(decimals-2)
+	}
+
+	return formatScaledAmount(int64(math.Round(scaled)), decimals)
+}
+
+// MarshalJSON emits the currency symbol alongside the code and amount
+// so API clients don't need their own currency table.
+func (p Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code   string `json:"code"`
+		Symbol string `json:"symbol"`
+		Amount string `json:"amount"`
+	}{
+		Code:   p.CurrencyCode,
+		Symbol: p.Symbol(),
+		Amount: p.Amount,
+	})
+}
+
+// UnmarshalJSON reads the code and amount as they were stored, without validating
+// or rounding them again, so stored events replay to the price they were saved with.
+// New input is validated by CreatePricePerUnit. The symbol is derived, not read.
+func (p *Money) UnmarshalJSON(data []byte) error {
+	raw := struct {
+		Code   string `json:"code"`
+		Amount string `json:"amount"`
+	}{}
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	*p = PricePerUnit{
+		Amount:       raw.Amount,
+		CurrencyCode: raw.Code,
+	}
+
+	return nil
+}
+
+// validatePriceAmount makes sure the amount parses as a non-negative number.
+// The amount itself is kept as a string to avoid float rounding.
+func validatePriceAmount(amount string) error {
+	a, err := strconv.ParseFloat(amount, 64)
+	if err != nil || math.IsNaN(a) || math.IsInf(a, 0) || a < 0 {
+		return MaterialError{Code: MaterialErrorInvalidPrice, Field: "price_per_unit"}
+	}
+
+	return nil
+}
+
+// CurrencyCode is the code of a supported currency.
+type CurrencyCode string
+
+const (
+	CurrencyEUR CurrencyCode = MoneyEUR
+	CurrencyIDR CurrencyCode = MoneyIDR
+	CurrencyUSD CurrencyCode = MoneyUSD
+)
+
+// Validate makes sure the code is one of the supported currencies, in upper case.
+func (c CurrencyCode) Validate() error {
+	switch c {
+	case CurrencyEUR, CurrencyIDR, CurrencyUSD:
+		return nil
 	default:
-		return "", errors.New("Wrong currency code")
+		return errors.New("Wrong currency code")
+	}
+}
+
+// ParseCurrencyCode trims and upper cases the code, so "eur" is read as EUR, and validates it.
+func ParseCurrencyCode(currencyCode string) (CurrencyCode, error) {
+	cc := CurrencyCode(strings.ToUpper(strings.TrimSpace(currencyCode)))
+
+	err := cc.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	return cc, nil
+}
+
+// GetCurrencyCode is ParseCurrencyCode for callers that keep the code as a string.
+func GetCurrencyCode(currencyCode string) (string, error) {
+	cc, err := ParseCurrencyCode(currencyCode)
+	if err != nil {
+		return "", err
 	}
+
+	return string(cc), nil
 }
 
 const (
@@ -75,17 +499,93 @@ const (
 	MaterialUnitUnits      = "UNITS"
 )
 
+// baseUnitConversion tells how many of BaseUnit one unit is worth.
+type baseUnitConversion struct {
+	BaseUnit string
+	Factor   float64
+}
+
+// Units like BAGS, PACKETS and BOTTLES have no fixed size, so they have no base unit
+var materialUnitBaseConversions = map[string]baseUnitConversion{
+	MaterialUnitGram:       {BaseUnit: MaterialUnitGram, Factor: 1},
+	MaterialUnitKilogram:   {BaseUnit: MaterialUnitGram, Factor: 1000},
+	MaterialUnitSeeds:      {BaseUnit: MaterialUnitPieces, Factor: 1},
+	MaterialUnitPieces:     {BaseUnit: MaterialUnitPieces, Factor: 1},
+	MaterialUnitUnits:      {BaseUnit: MaterialUnitPieces, Factor: 1},
+	MaterialUnitCubicMetre: {BaseUnit: MaterialUnitCubicMetre, Factor: 1},
+}
+
 type MaterialQuantity struct {
 	Value float32              `json:"value"`
 	Unit  MaterialQuantityUnit `json:"unit"`
 }
 
+// quantityEpsilon is how close to zero a quantity must be to count as zero,
+// so float32 rounding leftovers like 0.0000001 don't count as stock.
+const quantityEpsilon = 0.0001
+
+// IsZero tells whether the quantity is zero, within quantityEpsilon.
+func (q MaterialQuantity) IsZero() bool {
+	return math.Abs(float64(q.Value)) < quantityEpsilon
+}
+
+// IsPositive tells whether the quantity is greater than zero, beyond quantityEpsilon.
+func (q MaterialQuantity) IsPositive() bool {
+	return float64(q.Value) >= quantityEpsilon
+}
+
+// UnmarshalMaterialQuantity decodes a quantity sent by an API client, like
+// {"value": 20, "unit": {"code": "PACKETS"}}, and rejects a unit that doesn't belong to materialType.
+// The unit label is filled in from the unit list, whatever the client sent.
+func UnmarshalMaterialQuantity(data []byte, materialType MaterialType) (MaterialQuantity, error) {
+	q := MaterialQuantity{}
+
+	err := json.Unmarshal(data, &q)
+	if err != nil {
+		return MaterialQuantity{}, err
+	}
+
+	if materialType == nil {
+		return MaterialQuantity{}, MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}
+	}
+
+	qu, err := validateQuantityUnit(q.Unit.Code, materialType)
+	if err != nil {
+		return MaterialQuantity{}, err
+	}
+
+	err = validateQuantityGranularity(q.Value, qu.Code)
+	if err != nil {
+		return MaterialQuantity{}, err
+	}
+
+	return MaterialQuantity{Value: q.Value, Unit: qu}, nil
+}
+
 type MaterialQuantityUnit struct {
 	Code  string `json:"code"`
 	Label string `json:"label"`
 }
 
 func MaterialQuantityUnits(materialTypeCode string) []MaterialQuantityUnit {
+	units, _ := FindMaterialQuantityUnits(materialTypeCode)
+	return units
+}
+
+// MaterialQuantityUnitsE is like MaterialQuantityUnits but returns an error
+// for unknown material type codes instead of nil units.
+func MaterialQuantityUnitsE(materialTypeCode string) ([]MaterialQuantityUnit, error) {
+	units, ok := FindMaterialQuantityUnits(materialTypeCode)
+	if !ok {
+		return nil, MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}
+	}
+
+	return units, nil
+}
+
+// FindMaterialQuantityUnits returns the quantity units of a material type,
+// and false if the material type code is unknown.
+func FindMaterialQuantityUnits(materialTypeCode string) ([]MaterialQuantityUnit, bool) {
 	switch materialTypeCode {
 	case MaterialTypeSeedCode:
 		return []MaterialQuantityUnit{
@@ -93,262 +593,1942 @@ func MaterialQuantityUnits(materialTypeCode string) []MaterialQuantityUnit {
 			{Code: MaterialUnitPackets, Label: "Packets"},
 			{Code: MaterialUnitGram, Label: "Gram"},
 			{Code: MaterialUnitKilogram, Label: "Kilogram"},
-		}
+		}, true
 	case MaterialTypeAgrochemicalCode:
 		return []MaterialQuantityUnit{
 			{Code: MaterialUnitPackets, Label: "Packets"},
 			{Code: MaterialUnitBottles, Label: "Bottles"},
 			{Code: MaterialUnitBags, Label: "Bags"},
-		}
+		}, true
 	case MaterialTypeGrowingMediumCode:
 		return []MaterialQuantityUnit{
 			{Code: MaterialUnitBags, Label: "Bags"},
 			{Code: MaterialUnitCubicMetre, Label: "Cubic Metre"},
-		}
+		}, true
 	case MaterialTypeLabelAndCropSupportCode:
 		return []MaterialQuantityUnit{
 			{Code: MaterialUnitPieces, Label: "Pieces"},
-		}
+		}, true
 	case MaterialTypeSeedingContainerCode:
 		return []MaterialQuantityUnit{
 			{Code: MaterialUnitPieces, Label: "Pieces"},
-		}
+		}, true
 	case MaterialTypePostHarvestSupplyCode:
 		return []MaterialQuantityUnit{
 			{Code: MaterialUnitPieces, Label: "Pieces"},
-		}
+		}, true
 	case MaterialTypePlantCode:
 		return []MaterialQuantityUnit{
 			{Code: MaterialUnitUnits, Label: "Units"},
 			{Code: MaterialUnitPackets, Label: "Packets"},
-		}
+		}, true
 	case MaterialTypeOtherCode:
 		return []MaterialQuantityUnit{
 			{Code: MaterialUnitPieces, Label: "Pieces"},
+		}, true
+	}
+
+	return nil, false
+}
+
+// DefaultQuantityUnit is the unit to preselect for a material type, the first of its units,
+// and false if the material type code is unknown.
+func DefaultQuantityUnit(typeCode string) (MaterialQuantityUnit, bool) {
+	units, ok := FindMaterialQuantityUnits(typeCode)
+	if !ok || len(units) == 0 {
+		return MaterialQuantityUnit{}, false
+	}
+
+	return units[0], true
+}
+
+// materialUnitLabels translates the quantity unit labels, keyed by locale and then unit code.
+// English labels are the ones in FindMaterialQuantityUnits.
+var materialUnitLabels = map[string]map[string]string{
+	"id": {
+		MaterialUnitSeeds:      "Benih",
+		MaterialUnitPackets:    "Bungkus",
+		MaterialUnitGram:       "Gram",
+		MaterialUnitKilogram:   "Kilogram",
+		MaterialUnitBags:       "Karung",
+		MaterialUnitBottles:    "Botol",
+		MaterialUnitCubicMetre: "Meter Kubik",
+		MaterialUnitPieces:     "Buah",
+		MaterialUnitUnits:      "Unit",
+	},
+}
+
+// LocalizedMaterialQuantityUnits is like FindMaterialQuantityUnits but with the labels
+// translated to the language of locale (e.g. "id" or "id-ID"). Labels without a translation stay in English.
+func LocalizedMaterialQuantityUnits(materialTypeCode, locale string) ([]MaterialQuantityUnit, bool) {
+	units, ok := FindMaterialQuantityUnits(materialTypeCode)
+	if !ok {
+		return nil, false
+	}
+
+	labels := materialUnitLabels[localeLanguage(locale)]
+	for i, v := range units {
+		if label, ok := labels[v.Code]; ok {
+			units[i].Label = label
 		}
 	}
 
-	return nil
+	return units, true
 }
 
 func GetMaterialQuantityUnit(materialTypeCode string, code string) MaterialQuantityUnit {
+	qu, _ := GetMaterialQuantityUnitE(materialTypeCode, code)
+	return qu
+}
+
+// GetMaterialQuantityUnitE looks up a quantity unit of a material type,
+// and reports whether it was found.
+func GetMaterialQuantityUnitE(materialTypeCode string, code string) (MaterialQuantityUnit, bool) {
 	for _, v := range MaterialQuantityUnits(materialTypeCode) {
 		if v.Code == code {
-			return v
+			return v, true
 		}
 	}
 
-	return MaterialQuantityUnit{}
-}
-
-func (state *Material) TrackChange(event interface{}) {
-	state.UncommittedChanges = append(state.UncommittedChanges, event)
-	state.Transition(event)
+	return MaterialQuantityUnit{}, false
 }
 
-func (state *Material) Transition(event interface{}) {
-	switch e := event.(type) {
-	case MaterialCreated:
-		state.UID = e.UID
-		state.Name = e.Name
-		state.PricePerUnit = e.PricePerUnit
-		state.Type = e.Type
-		state.Quantity = e.Quantity
-		state.ExpirationDate = e.ExpirationDate
-		state.Notes = e.Notes
-		state.ProducedBy = e.ProducedBy
-		state.CreatedDate = e.CreatedDate
+// ConvertQuantity converts value from one quantity unit to another.
+// Only units that share the same base unit can be converted (e.g. GRAM and KILOGRAM).
+func ConvertQuantity(value float32, from, to string) (float32, error) {
+	fromConv, ok := materialUnitBaseConversions[from]
+	if !ok {
+		return 0, errors.New("cannot convert from " + from)
+	}
 
-	case MaterialNameChanged:
-		state.Name = e.Name
+	toConv, ok := materialUnitBaseConversions[to]
+	if !ok {
+		return 0, errors.New("cannot convert to " + to)
+	}
 
-	case MaterialTypeChanged:
-		state.Type = e.MaterialType
+	if fromConv.BaseUnit != toConv.BaseUnit {
+		return 0, errors.New("cannot convert " + from + " to " + to)
+	}
 
-	case MaterialPriceChanged:
-		state.PricePerUnit = e.Price
+	return float32(float64(value) * fromConv.Factor / toConv.Factor), nil
+}
 
-	case MaterialQuantityChanged:
-		state.Quantity = e.Quantity
+// PricePerBaseUnit normalizes the price to the canonical base unit of the
+// material quantity unit (grams for mass, pieces for count), so prices of
+// materials stocked in different units can be compared.
+func (m Material) PricePerBaseUnit() (float64, string, error) {
+	conv, ok := materialUnitBaseConversions[m.Quantity.Unit.Code]
+	if !ok {
+		return 0, "", errors.New("quantity unit has no base unit")
+	}
+
+	amount, err := m.PricePerUnit.AmountFloat()
+	if err != nil {
+		return 0, "", err
+	}
+
+	return amount / conv.Factor, conv.BaseUnit, nil
+}
+
+// TotalInventoryValue sums price times quantity of the materials priced in currency.
+// Materials priced in other currencies are skipped.
+func TotalInventoryValue(materials []Material, currency string) (Money, error) {
+	cc, err := GetCurrencyCode(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	total := 0.0
+	for _, v := range materials {
+		if v.PricePerUnit.CurrencyCode != cc {
+			continue
+		}
+
+		minorUnits, err := v.PricePerUnit.MinorUnits()
+		if err != nil {
+			return Money{}, err
+		}
+
+		total += float64(minorUnits) * float64(v.Quantity.Value)
+	}
+
+	return Money{
+		Amount:       formatAmount(total, cc),
+		CurrencyCode: cc,
+	}, nil
+}
+
+// NewMaterialFromHistory rebuilds a Material by replaying its stored events.
+func NewMaterialFromHistory(events []interface{}) *Material {
+	state := &Material{}
+	for _, v := range events {
+		state.Transition(v)
+		state.Version++
+	}
+	return state
+}
+
+func (state *Material) TrackChange(event interface{}) {
+	state.UncommittedChanges = append(state.UncommittedChanges, event)
+	state.Transition(event)
+	state.Version++
+}
+
+// MarkChangesCommitted clears UncommittedChanges once they are persisted,
+// so they won't be saved twice. Version is left as it is.
+func (state *Material) MarkChangesCommitted() {
+	state.UncommittedChanges = []interface{}{}
+}
+
+// Clone returns a deep copy of the material that shares no pointer fields with the original.
+// UncommittedChanges is reset on the copy, Version is kept.
+func (state *Material) Clone() *Material {
+	clone := *state
+
+	if state.ExpirationDate != nil {
+		expDate := *state.ExpirationDate
+		clone.ExpirationDate = &expDate
+	}
+	if state.Notes != nil {
+		notes := *state.Notes
+		clone.Notes = &notes
+	}
+	if state.ProducedBy != nil {
+		producedBy := *state.ProducedBy
+		clone.ProducedBy = &producedBy
+	}
+	if state.IsExpense != nil {
+		isExpense := *state.IsExpense
+		clone.IsExpense = &isExpense
+	}
+	if state.SupplierID != nil {
+		supplierID := *state.SupplierID
+		clone.SupplierID = &supplierID
+	}
+	if state.ExternalID != nil {
+		externalID := *state.ExternalID
+		clone.ExternalID = &externalID
+	}
+	if state.LowStockThreshold != nil {
+		threshold := *state.LowStockThreshold
+		clone.LowStockThreshold = &threshold
+	}
+
+	clone.Lots = nil
+	for _, v := range state.Lots {
+		if v.ExpirationDate != nil {
+			expDate := *v.ExpirationDate
+			v.ExpirationDate = &expDate
+		}
+
+		clone.Lots = append(clone.Lots, v)
+	}
+
+	clone.Tags = append([]string(nil), state.Tags...)
+	clone.Components = append([]MaterialComponent(nil), state.Components...)
+	clone.Attachments = append([]Attachment(nil), state.Attachments...)
+
+	clone.SupplierSKUs = nil
+	if state.SupplierSKUs != nil {
+		clone.SupplierSKUs = make(map[uuid.UUID]string, len(state.SupplierSKUs))
+		for k, v := range state.SupplierSKUs {
+			clone.SupplierSKUs[k] = v
+		}
+	}
+
+	clone.history = append([]ChangeRecord(nil), state.history...)
+	clone.priceHistory = append([]PricedAt(nil), state.priceHistory...)
+	clone.UncommittedChanges = []interface{}{}
+
+	return &clone
+}
+
+// MaterialSnapshot is the state of a material at a given version,
+// so it can be loaded without replaying its whole history.
+// History and PriceHistory carry the histories State keeps unexported,
+// so a stored snapshot restores them too.
+type MaterialSnapshot struct {
+	State        Material
+	Version      int
+	History      []ChangeRecord
+	PriceHistory []PricedAt
+}
+
+// ToSnapshot captures the current state and version of the material.
+func (state *Material) ToSnapshot() MaterialSnapshot {
+	clone := state.Clone()
+	clone.UncommittedChanges = nil
+
+	return MaterialSnapshot{
+		State:        *clone,
+		Version:      state.Version,
+		History:      state.ChangeHistory(),
+		PriceHistory: state.PriceHistory(),
+	}
+}
+
+// NewMaterialFromSnapshot restores the material from the snapshot
+// and replays the events that happened after it.
+func NewMaterialFromSnapshot(snap MaterialSnapshot, laterEvents []interface{}) *Material {
+	state := snap.State.Clone()
+	state.UncommittedChanges = nil
+	state.Version = snap.Version
+	state.history = append([]ChangeRecord(nil), snap.History...)
+	state.priceHistory = append([]PricedAt(nil), snap.PriceHistory...)
+
+	for _, v := range laterEvents {
+		state.Transition(v)
+		state.Version++
+	}
+	return state
+}
+
+// BaseVersion is the version the material had before its uncommitted changes,
+// which is what the event repository expects as the latest persisted version.
+func (state *Material) BaseVersion() int {
+	return state.Version - len(state.UncommittedChanges)
+}
+
+func (state *Material) Transition(event interface{}) {
+	switch e := event.(type) {
+	case MaterialCreated:
+		state.UID = e.UID
+		state.Name = e.Name
+		state.PricePerUnit = e.PricePerUnit
+		state.Type = e.Type
+		state.Quantity = e.Quantity
+		state.ExpirationDate = e.ExpirationDate
+		state.Notes = e.Notes
+		state.ProducedBy = e.ProducedBy
+		state.IsExpense = e.IsExpense
+		state.SupplierID = e.SupplierID
+		state.ExternalID = e.ExternalID
+		state.CreatedDate = e.CreatedDate
+
+		state.priceHistory = append(state.priceHistory, PricedAt{Price: e.PricePerUnit, EffectiveFrom: e.CreatedDate})
+
+		if e.EventVersion < 1 && state.IsExpense == nil && state.Type != nil {
+			isExpense := DefaultIsExpense(state.Type)
+			state.IsExpense = &isExpense
+		}
+
+	case MaterialNameChanged:
+		state.Name = e.Name
+
+		state.recordChange("name", e.Name, e.ChangedAt, e.ChangedBy)
+
+	case MaterialQuantityConsumed:
+		state.Quantity.Value -= e.Amount
+
+	case MaterialQuantityRestocked:
+		state.Quantity.Value += e.Amount
+
+		if e.ExpirationDate != nil {
+			state.ExpirationDate = e.ExpirationDate
+		}
+
+	case MaterialMerged:
+		state.Quantity.Value += e.Amount
+		state.Reserved += e.Reserved
+		state.ExpirationDate = e.ExpirationDate
+		state.Lots = mergeLots(state.Lots, e.Lots)
+
+	case MaterialLotAdded:
+		state.Lots = append(state.Lots, e.Lot)
+		state.Quantity.Value += e.Lot.Quantity
+
+	case MaterialLotConsumed:
+		for i, v := range state.Lots {
+			if v.LotNumber == e.LotNumber {
+				state.Lots[i].Quantity -= e.Amount
+
+				if state.Lots[i].Quantity <= 0 {
+					state.Lots = append(state.Lots[:i], state.Lots[i+1:]...)
+				}
+
+				break
+			}
+		}
+
+		state.Quantity.Value -= e.Amount
+
+	case MaterialQuantityReserved:
+		state.Reserved += e.Amount
+
+	case MaterialReservationReleased:
+		state.Reserved -= e.Amount
+
+	case MaterialLowStockThresholdChanged:
+		state.LowStockThreshold = e.Threshold
+
+	case MaterialTypeChanged:
+		state.Type = e.MaterialType
+
+		if e.QuantityUnit != (MaterialQuantityUnit{}) {
+			state.Quantity.Unit = e.QuantityUnit
+		}
+
+		state.recordChange("type", e.MaterialType.Code(), e.ChangedAt, e.ChangedBy)
+
+	case MaterialPriceChanged:
+		state.PricePerUnit = e.Price
+
+		state.recordChange("price_per_unit", formatPrice(e.Price), e.ChangedAt, e.ChangedBy)
+		state.priceHistory = append(state.priceHistory, PricedAt{Price: e.Price, EffectiveFrom: e.ChangedAt})
+
+	case MaterialQuantityChanged:
+		state.Quantity = e.Quantity
+
+		state.recordChange("quantity", formatQuantity(e.Quantity), e.ChangedAt, e.ChangedBy)
 
 	case MaterialExpirationDateChanged:
-		state.ExpirationDate = &e.ExpirationDate
+		state.ExpirationDate = e.ExpirationDate
+
+		state.recordChange("expiration_date", formatOptionalTime(e.ExpirationDate), e.ChangedAt, e.ChangedBy)
+
+	case MaterialNotesChanged:
+		state.Notes = e.Notes
+
+		state.recordChange("notes", formatOptionalString(e.Notes), e.ChangedAt, e.ChangedBy)
+
+	case MaterialProducedByChanged:
+		state.ProducedBy = &e.ProducedBy
+
+		state.recordChange("produced_by", e.ProducedBy, e.ChangedAt, e.ChangedBy)
+
+	case MaterialSupplierChanged:
+		state.SupplierID = e.SupplierID
+
+		value := ""
+		if e.SupplierID != nil {
+			value = e.SupplierID.String()
+		}
+		state.recordChange("supplier_id", value, e.ChangedAt, e.ChangedBy)
+
+	case MaterialPlantTypeChanged:
+		packaging := PlantPackagingUnspecified
+		if mt, ok := state.Type.(MaterialTypePlant); ok {
+			packaging = mt.Packaging
+		}
+
+		state.Type = MaterialTypePlant{PlantType: e.PlantType, Packaging: packaging}
+
+		state.recordChange("plant_type", e.PlantType.Code, e.ChangedAt, e.ChangedBy)
+
+	case MaterialExpired:
+		state.IsExpired = true
+
+	case MaterialArchived:
+		state.Archived = true
+
+	case MaterialUnarchived:
+		state.Archived = false
+
+	case MaterialComponentAdded:
+		state.Components = append(state.Components, e.Component)
+
+	case MaterialComponentRemoved:
+		for i, v := range state.Components {
+			if v.MaterialUID == e.ComponentUID {
+				state.Components = append(state.Components[:i:i], state.Components[i+1:]...)
+				break
+			}
+		}
+
+	case MaterialAttachmentAdded:
+		state.Attachments = append(state.Attachments, e.Attachment)
+
+	case MaterialAttachmentRemoved:
+		for i, v := range state.Attachments {
+			if v.Key == e.Key {
+				state.Attachments = append(state.Attachments[:i:i], state.Attachments[i+1:]...)
+				break
+			}
+		}
+
+	case MaterialTagAdded:
+		state.Tags = append(state.Tags, e.Tag)
+
+	case MaterialTagRemoved:
+		for i, v := range state.Tags {
+			if v == e.Tag {
+				state.Tags = append(state.Tags[:i:i], state.Tags[i+1:]...)
+				break
+			}
+		}
+
+	case MaterialSupplierSKUSet:
+		if state.SupplierSKUs == nil {
+			state.SupplierSKUs = make(map[uuid.UUID]string)
+		}
+
+		state.SupplierSKUs[e.SupplierID] = e.SKU
+
+	case MaterialSupplierSKURemoved:
+		delete(state.SupplierSKUs, e.SupplierID)
+
+	}
+
+	state.checkInvariants()
+}
+
+// checkInvariants keeps the quantities an event can push below zero at zero,
+// whatever operation emitted the event. It runs after every transition,
+// replayed or new, so a stored history that overdraws the stock still loads.
+// It also rounds the quantities to MaterialQuantityDecimals, so float32 drift
+// like 0.30000001 doesn't build up over many consumptions.
+func (state *Material) checkInvariants() {
+	state.Quantity.Value = roundQuantity(state.Quantity.Value)
+	state.Reserved = roundQuantity(state.Reserved)
+
+	if state.Quantity.Value < 0 {
+		state.Quantity.Value = 0
+	}
+
+	if state.Reserved < 0 {
+		state.Reserved = 0
+	}
+
+	if state.Reserved > state.Quantity.Value {
+		state.Reserved = state.Quantity.Value
+	}
+
+	for i := range state.Lots {
+		state.Lots[i].Quantity = roundQuantity(state.Lots[i].Quantity)
+
+		if state.Lots[i].Quantity < 0 {
+			state.Lots[i].Quantity = 0
+		}
+	}
+
+	// The stock outside of lots can't be negative either, or the lots would be
+	// counted twice when they are consumed.
+	if lots := state.lotQuantity(); state.Quantity.Value < lots {
+		state.Quantity.Value = lots
+	}
+}
+
+// MaterialQuantityDecimals is the number of decimals material quantities are rounded to
+// after every transition.
+var MaterialQuantityDecimals = 3
+
+func roundQuantity(value float32) float32 {
+	p := math.Pow(10, float64(MaterialQuantityDecimals))
+
+	return float32(math.Round(float64(value)*p) / p)
+}
+
+func (state *Material) recordChange(field, value string, changedAt time.Time, changedBy uuid.UUID) {
+	state.history = append(state.history, ChangeRecord{
+		Field:     field,
+		Value:     value,
+		ChangedAt: changedAt,
+		ChangedBy: changedBy,
+	})
+}
+
+// ChangeHistory lists who changed what and when, oldest first.
+// It covers the replayed events as well as the uncommitted ones.
+func (state *Material) ChangeHistory() []ChangeRecord {
+	history := make([]ChangeRecord, len(state.history))
+	copy(history, state.history)
+
+	return history
+}
+
+// PriceHistory lists the prices the material had, oldest first,
+// from its creation and from every price change.
+func (state *Material) PriceHistory() []PricedAt {
+	return append([]PricedAt(nil), state.priceHistory...)
+}
+
+// PriceAt returns the price effective at date, and false when the material had no price yet.
+func (state *Material) PriceAt(date time.Time) (PricePerUnit, bool) {
+	price, found := PricePerUnit{}, false
+	for _, v := range state.priceHistory {
+		if v.EffectiveFrom.After(date) {
+			break
+		}
+
+		price, found = v.Price, true
+	}
+
+	return price, found
+}
+
+// FieldChange is a field that differs between two materials,
+// with the values formatted like in ChangeRecord.
+type FieldChange struct {
+	Field    string `json:"field"`
+	OldValue string `json:"old_value"`
+	NewValue string `json:"new_value"`
+}
+
+// Diff lists the fields that change from the material to other, e.g. to preview an edit
+// before saving it. It compares the name, price, quantity, expiration date and notes.
+func (state *Material) Diff(other *Material) []FieldChange {
+	changes := []FieldChange{}
+
+	add := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, FieldChange{Field: field, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	add("name", state.Name, other.Name)
+	if !state.PricePerUnit.Equals(other.PricePerUnit) {
+		add("price_per_unit", formatPrice(state.PricePerUnit), formatPrice(other.PricePerUnit))
+	}
+	add("quantity", formatQuantity(state.Quantity), formatQuantity(other.Quantity))
+	add("expiration_date", formatOptionalTime(state.ExpirationDate), formatOptionalTime(other.ExpirationDate))
+	add("notes", formatOptionalString(state.Notes), formatOptionalString(other.Notes))
+
+	return changes
+}
+
+func formatPrice(p PricePerUnit) string {
+	return p.Amount + " " + p.CurrencyCode
+}
+
+func formatQuantity(q MaterialQuantity) string {
+	return strconv.FormatFloat(float64(q.Value), 'f', -1, 32) + " " + q.Unit.Code
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+func formatOptionalString(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+// NewMaterialUID generates the UID of new materials.
+// Tests can replace it to get deterministic UIDs.
+var NewMaterialUID = uuid.NewV4
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock of the system, it calls time.Now()
+type SystemClock struct{}
+
+func (c SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// MaterialClock is where materials read the current time from, e.g. for their created date.
+// Tests can replace it with a fixed clock.
+var MaterialClock Clock = SystemClock{}
+
+// DefaultIsExpense tells whether a material type is an expense when not stated otherwise.
+// Plants are usually produced by the farm itself, everything else is bought.
+func DefaultIsExpense(materialType MaterialType) bool {
+	return materialType.Code() != MaterialTypePlantCode
+}
+
+// CreateMaterial registers a new material.
+// A nil isExpense is resolved with DefaultIsExpense, so IsExpense is never stored as nil.
+func CreateMaterial(
+	name string,
+	price string,
+	priceUnit string,
+	materialType MaterialType,
+	quantity float32,
+	quantityUnit string,
+	expirationDate *time.Time,
+	notes *string,
+	producedBy *string,
+	isExpense *bool,
+	supplierID *uuid.UUID,
+	externalID *string) (*Material, error) {
+
+	name = strings.TrimSpace(name)
+
+	err := validateName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := NewMaterialUID()
+	if err != nil {
+		return nil, err
+	}
+
+	pricePerUnit, err := CreatePricePerUnit(price, priceUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	if materialType == nil {
+		return nil, MaterialError{Code: MaterialErrorEmptyValue, Field: "type"}
+	}
+
+	err = validateQuantity(quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	qu, err := validateQuantityUnit(quantityUnit, materialType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateQuantityGranularity(quantity, qu.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if producedBy != nil {
+		err = validateProducedBy(*producedBy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	createdDate := MaterialClock.Now()
+
+	err = validateExpirationDate(expirationDate, createdDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if isExpense == nil {
+		ie := DefaultIsExpense(materialType)
+		isExpense = &ie
+	}
+
+	if externalID != nil {
+		trimmed := strings.TrimSpace(*externalID)
+		externalID = &trimmed
+
+		if trimmed == "" {
+			externalID = nil
+		}
+	}
+
+	initial := &Material{
+		UID:          uid,
+		Name:         name,
+		PricePerUnit: pricePerUnit,
+		Type:         materialType,
+		Quantity: MaterialQuantity{
+			Value: quantity,
+			Unit:  qu,
+		},
+		ExpirationDate: expirationDate,
+		Notes:          notes,
+		ProducedBy:     producedBy,
+		IsExpense:      isExpense,
+		SupplierID:     supplierID,
+		ExternalID:     externalID,
+		CreatedDate:    createdDate,
+	}
+
+	initial.TrackChange(MaterialCreated{
+		EventVersion:   MaterialCreatedEventVersion,
+		UID:            initial.UID,
+		Name:           initial.Name,
+		PricePerUnit:   initial.PricePerUnit,
+		Type:           initial.Type,
+		Quantity:       initial.Quantity,
+		ExpirationDate: initial.ExpirationDate,
+		Notes:          initial.Notes,
+		ProducedBy:     initial.ProducedBy,
+		IsExpense:      initial.IsExpense,
+		SupplierID:     initial.SupplierID,
+		ExternalID:     initial.ExternalID,
+		CreatedDate:    initial.CreatedDate,
+	})
+
+	return initial, nil
+}
+
+// MaterialSpec holds the arguments of CreateMaterial, so materials can be created in batch
+type MaterialSpec struct {
+	Name           string
+	Price          string
+	PriceUnit      string
+	Type           MaterialType
+	Quantity       float32
+	QuantityUnit   string
+	ExpirationDate *time.Time
+	Notes          *string
+	ProducedBy     *string
+	IsExpense      *bool
+	SupplierID     *uuid.UUID
+	ExternalID     *string
+}
+
+// MaterialSpecError is the error of a single spec of a batch.
+// Index is the position of the spec in the batch.
+type MaterialSpecError struct {
+	Index int
+	Err   error
+}
+
+func (e MaterialSpecError) Error() string {
+	return "material " + strconv.Itoa(e.Index) + ": " + e.Err.Error()
+}
+
+// MaterialSpecErrors combines the errors of every invalid spec of a batch
+type MaterialSpecErrors []MaterialSpecError
+
+func (e MaterialSpecErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, v := range e {
+		messages[i] = v.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// CreateMaterials creates a material for every spec, or none at all when a spec is invalid.
+// The error is then a MaterialSpecErrors with the error of every invalid spec.
+func CreateMaterials(specs []MaterialSpec) ([]*Material, error) {
+	materials := []*Material{}
+	specErrors := MaterialSpecErrors{}
+
+	for i, v := range specs {
+		material, err := CreateMaterial(v.Name, v.Price, v.PriceUnit, v.Type, v.Quantity, v.QuantityUnit, v.ExpirationDate, v.Notes, v.ProducedBy, v.IsExpense, v.SupplierID, v.ExternalID)
+		if err != nil {
+			specErrors = append(specErrors, MaterialSpecError{Index: i, Err: err})
+			continue
+		}
+
+		materials = append(materials, material)
+	}
+
+	if len(specErrors) > 0 {
+		return nil, specErrors
+	}
+
+	return materials, nil
+}
+
+// CreateProducedMaterial registers the produce of a harvested crop as a material.
+// Only plants and post harvest supplies can be produced by the farm, so they are
+// never an expense. The MaterialProduced event links the material back to the crop.
+func CreateProducedMaterial(
+	name string,
+	price string,
+	priceUnit string,
+	materialType MaterialType,
+	quantity float32,
+	quantityUnit string,
+	expirationDate *time.Time,
+	notes *string,
+	cropUID uuid.UUID) (*Material, error) {
+
+	if materialType == nil {
+		return nil, MaterialError{Code: MaterialErrorEmptyValue, Field: "type"}
+	}
+
+	switch materialType.Code() {
+	case MaterialTypePlantCode, MaterialTypePostHarvestSupplyCode:
+	default:
+		return nil, MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}
+	}
+
+	producedBy := ProducedByInternal
+	isExpense := false
+
+	material, err := CreateMaterial(name, price, priceUnit, materialType, quantity, quantityUnit, expirationDate, notes, &producedBy, &isExpense, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	material.TrackChange(MaterialProduced{
+		MaterialUID:  material.UID,
+		CropUID:      cropUID,
+		Quantity:     material.Quantity,
+		ProducedDate: material.CreatedDate,
+	})
+
+	return material, nil
+}
+
+func (m *Material) ChangeName(name string, changedAt time.Time, changedBy uuid.UUID) error {
+	name = strings.TrimSpace(name)
+
+	err := validateName(name)
+	if err != nil {
+		return err
+	}
+
+	if name == m.Name {
+		return nil
+	}
+
+	m.TrackChange(MaterialNameChanged{
+		MaterialUID: m.UID,
+		Name:        name,
+		ChangedAt:   changedAt,
+		ChangedBy:   changedBy,
+	})
+
+	return nil
+}
+
+func (m *Material) ChangePricePerUnit(price, priceUnit string, changedAt time.Time, changedBy uuid.UUID) error {
+	ppu, err := CreatePricePerUnit(price, priceUnit)
+	if err != nil {
+		return err
+	}
+
+	if m.PricePerUnit.CurrencyCode != "" && m.PricePerUnit.CurrencyCode != ppu.CurrencyCode {
+		return errors.New("cannot change currency of existing material")
+	}
+
+	if ppu.Equals(m.PricePerUnit) {
+		return nil
+	}
+
+	m.TrackChange(MaterialPriceChanged{
+		MaterialUID: m.UID,
+		Price:       ppu,
+		ChangedAt:   changedAt,
+		ChangedBy:   changedBy,
+	})
+
+	return nil
+}
+
+// ChangePriceCurrency is the explicit way to reprice a material in another currency,
+// since ChangePricePerUnit refuses to switch currencies.
+func (m *Material) ChangePriceCurrency(price, priceUnit string, changedAt time.Time, changedBy uuid.UUID) error {
+	ppu, err := CreatePricePerUnit(price, priceUnit)
+	if err != nil {
+		return err
+	}
+
+	m.TrackChange(MaterialPriceChanged{
+		MaterialUID: m.UID,
+		Price:       ppu,
+		ChangedAt:   changedAt,
+		ChangedBy:   changedBy,
+	})
+
+	return nil
+}
+
+// ChangeQuantityUnit sets the quantity and its unit. The quantity can't go below
+// what is reserved, as that would consume reserved stock, nor below the lots.
+// The unit of a material with lots can't change, since the lots are counted in it.
+func (m *Material) ChangeQuantityUnit(quantity float32, quantityUnit string, materialType MaterialType, changedAt time.Time, changedBy uuid.UUID) error {
+	err := validateQuantity(quantity)
+	if err != nil {
+		return err
+	}
+
+	qu, err := validateQuantityUnit(quantityUnit, materialType)
+	if err != nil {
+		return err
+	}
+
+	err = m.validateQuantityChange(quantity, qu)
+	if err != nil {
+		return err
+	}
+
+	if quantity == m.Quantity.Value && qu == m.Quantity.Unit {
+		return nil
+	}
+
+	m.TrackChange(MaterialQuantityChanged{
+		MaterialUID: m.UID,
+		Quantity: MaterialQuantity{
+			Value: quantity,
+			Unit:  qu,
+		},
+		MaterialTypeCode: materialType.Code(),
+		ChangedAt:        changedAt,
+		ChangedBy:        changedBy,
+	})
+
+	return nil
+}
+
+// validateQuantityChange checks that the stock can become quantity in qu:
+// a whole number for units that can't be split, no less than what is reserved
+// or tracked in lots, and a unit change only when no reservation or lot is
+// counted in the current unit.
+func (m *Material) validateQuantityChange(quantity float32, qu MaterialQuantityUnit) error {
+	err := validateQuantityGranularity(quantity, qu.Code)
+	if err != nil {
+		return err
+	}
+
+	if quantity < m.Reserved {
+		return errors.New("insufficient available quantity")
+	}
+
+	if qu != m.Quantity.Unit && m.Reserved > 0 {
+		return errors.New("cannot change the quantity unit of a material with reservations")
+	}
+
+	if len(m.Lots) > 0 {
+		if qu != m.Quantity.Unit {
+			return errors.New("cannot change the quantity unit of a material with lots")
+		}
+
+		if quantity < m.lotQuantity() {
+			return errors.New("quantity cannot be less than the quantity in lots")
+		}
+	}
+
+	return nil
+}
+
+// CorrectQuantity sets the quantity value in the unit it already has,
+// e.g. to fix a counting error, so the unit can't be changed by accident.
+// The change is dated by MaterialClock and has no known author.
+func (m *Material) CorrectQuantity(value float32) error {
+	if m.Type == nil {
+		return MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}
+	}
+
+	return m.ChangeQuantityUnit(value, m.Quantity.Unit.Code, m.Type, MaterialClock.Now(), uuid.Nil)
+}
+
+// ConsumeQuantity takes amount out of the material stock, e.g. when a crop uses it.
+// Reserved stock can't be consumed, so amount must not exceed AvailableQuantity.
+// When the material has lots, they are used first, in the order of ConsumeFromLot,
+// and only what they can't cover is taken from the stock outside of lots.
+func (m *Material) ConsumeQuantity(amount float32) error {
+	if amount <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	err := validateQuantityGranularity(amount, m.Quantity.Unit.Code)
+	if err != nil {
+		return err
+	}
+
+	remaining := MaterialQuantity{Value: m.AvailableQuantity() - amount, Unit: m.Quantity.Unit}
+	if remaining.IsZero() {
+		// Use up the available stock exactly instead of leaving a rounding leftover
+		amount = m.AvailableQuantity()
+	} else if !remaining.IsPositive() {
+		if amount > m.Quantity.Value {
+			return errors.New("insufficient quantity")
+		}
+
+		return errors.New("insufficient available quantity")
+	}
+
+	fromLots := m.lotQuantity()
+	if fromLots > amount {
+		fromLots = amount
+	}
+
+	if fromLots > 0 {
+		m.consumeLots(fromLots)
+		amount -= fromLots
+	}
+
+	if !(MaterialQuantity{Value: amount}).IsPositive() {
+		return nil
+	}
+
+	m.TrackChange(MaterialQuantityConsumed{
+		MaterialUID: m.UID,
+		Amount:      amount,
+	})
+
+	return nil
+}
+
+// RestockQuantity adds amount to the material stock. When the restocked batch
+// comes with its own expiration date, pass it to replace the current one.
+func (m *Material) RestockQuantity(amount float32, expirationDate *time.Time) error {
+	if amount <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	err := validateQuantityGranularity(amount, m.Quantity.Unit.Code)
+	if err != nil {
+		return err
+	}
+
+	m.TrackChange(MaterialQuantityRestocked{
+		MaterialUID:    m.UID,
+		Amount:         amount,
+		ExpirationDate: expirationDate,
+	})
+
+	return nil
+}
+
+// Split moves amount out of the material into a new material with a fresh UID,
+// e.g. when part of a batch goes to another storage location. The new material
+// keeps the name, price, type, expiration date, notes and source of this one.
+// Reserved amounts can't be split off.
+func (m *Material) Split(amount float32) (*Material, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+
+	if amount > m.AvailableQuantity() {
+		return nil, errors.New("insufficient available quantity")
+	}
+
+	err := validateQuantityGranularity(amount, m.Quantity.Unit.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := NewMaterialUID()
+	if err != nil {
+		return nil, err
+	}
+
+	clone := m.Clone()
+
+	split := &Material{
+		UID:          uid,
+		Name:         clone.Name,
+		PricePerUnit: clone.PricePerUnit,
+		Type:         clone.Type,
+		Quantity: MaterialQuantity{
+			Value: amount,
+			Unit:  clone.Quantity.Unit,
+		},
+		ExpirationDate: clone.ExpirationDate,
+		Notes:          clone.Notes,
+		ProducedBy:     clone.ProducedBy,
+		IsExpense:      clone.IsExpense,
+		SupplierID:     clone.SupplierID,
+		CreatedDate:    MaterialClock.Now(),
+	}
+
+	split.TrackChange(MaterialCreated{
+		EventVersion:   MaterialCreatedEventVersion,
+		UID:            split.UID,
+		Name:           split.Name,
+		PricePerUnit:   split.PricePerUnit,
+		Type:           split.Type,
+		Quantity:       split.Quantity,
+		ExpirationDate: split.ExpirationDate,
+		Notes:          split.Notes,
+		ProducedBy:     split.ProducedBy,
+		IsExpense:      split.IsExpense,
+		SupplierID:     split.SupplierID,
+		CreatedDate:    split.CreatedDate,
+	})
+
+	err = m.ConsumeQuantity(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return split, nil
+}
+
+// Merge moves the quantity of a duplicate entry of the same material into this one,
+// with its lots and reservations, keeping the earliest expiration date, and archives
+// the other material. Both must have the same type, currency and quantity unit.
+func (m *Material) Merge(other *Material) error {
+	if other == nil || other.UID == m.UID {
+		return errors.New("cannot merge a material with itself")
+	}
+
+	if other.Archived {
+		return errors.New("material is already archived")
+	}
+
+	if m.Type != other.Type {
+		return errors.New("cannot merge materials of different types")
+	}
+
+	if m.PricePerUnit.CurrencyCode != other.PricePerUnit.CurrencyCode {
+		return errors.New("cannot merge materials of different currencies")
+	}
+
+	if m.Quantity.Unit.Code != other.Quantity.Unit.Code {
+		return errors.New("cannot merge materials of different quantity units")
+	}
+
+	expirationDate := m.ExpirationDate
+	if other.ExpirationDate != nil && (expirationDate == nil || other.ExpirationDate.Before(*expirationDate)) {
+		expDate := *other.ExpirationDate
+		expirationDate = &expDate
+	}
+
+	lots := make([]MaterialLot, len(other.Lots))
+	copy(lots, other.Lots)
+
+	amount := other.Quantity.Value
+	reserved := other.Reserved
+
+	// Archive the other material first, so a failure leaves this one untouched
+	err := other.Archive()
+	if err != nil {
+		return err
+	}
+
+	m.TrackChange(MaterialMerged{
+		MaterialUID:       m.UID,
+		MergedMaterialUID: other.UID,
+		Amount:            amount,
+		Lots:              lots,
+		Reserved:          reserved,
+		ExpirationDate:    expirationDate,
+	})
+
+	return nil
+}
+
+// mergeLots adds the lots of a merged material to lots. A lot number both have
+// is one lot, so its quantities are summed and the earliest expiration date kept.
+func mergeLots(lots []MaterialLot, merged []MaterialLot) []MaterialLot {
+	for _, v := range merged {
+		found := false
+		for i := range lots {
+			if lots[i].LotNumber != v.LotNumber {
+				continue
+			}
+
+			lots[i].Quantity += v.Quantity
+			if v.ExpirationDate != nil && (lots[i].ExpirationDate == nil || v.ExpirationDate.Before(*lots[i].ExpirationDate)) {
+				lots[i].ExpirationDate = v.ExpirationDate
+			}
+
+			found = true
+			break
+		}
+
+		if !found {
+			lots = append(lots, v)
+		}
+	}
+
+	return lots
+}
+
+// ConsumeQuantityIn is like ConsumeQuantity for an amount given in unitCode,
+// which is converted to the unit of the material. It can't consume reserved stock either.
+// Units that can't be converted, like PIECES for a material stocked in GRAM, are rejected.
+func (m *Material) ConsumeQuantityIn(amount float32, unitCode string) error {
+	err := validateQuantityGranularity(amount, unitCode)
+	if err != nil {
+		return err
+	}
+
+	converted, err := m.toStoredUnit(amount, unitCode)
+	if err != nil {
+		return err
+	}
+
+	return m.ConsumeQuantity(converted)
+}
+
+// RestockQuantityIn is like RestockQuantity for an amount given in unitCode,
+// which is converted to the unit of the material.
+func (m *Material) RestockQuantityIn(amount float32, unitCode string, expirationDate *time.Time) error {
+	converted, err := m.toStoredUnit(amount, unitCode)
+	if err != nil {
+		return err
+	}
+
+	return m.RestockQuantity(converted, expirationDate)
+}
+
+func (m *Material) toStoredUnit(amount float32, unitCode string) (float32, error) {
+	if unitCode == m.Quantity.Unit.Code {
+		return amount, nil
+	}
+
+	converted, err := ConvertQuantity(amount, unitCode, m.Quantity.Unit.Code)
+	if err != nil {
+		return 0, MaterialError{Code: MaterialErrorInvalidQuantityUnit, Field: "quantity_unit"}
+	}
+
+	return converted, nil
+}
+
+// Reserve sets amount aside for a planned use without consuming it yet.
+// Only the available quantity, which excludes earlier reservations, can be reserved.
+func (m *Material) Reserve(amount float32) error {
+	if amount <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	if amount > m.AvailableQuantity() {
+		return errors.New("insufficient available quantity")
+	}
+
+	err := validateQuantityGranularity(amount, m.Quantity.Unit.Code)
+	if err != nil {
+		return err
+	}
+
+	m.TrackChange(MaterialQuantityReserved{
+		MaterialUID: m.UID,
+		Amount:      amount,
+	})
+
+	return nil
+}
+
+// ReleaseReservation gives back a reserved amount. Releasing more than is
+// reserved only releases what is reserved.
+func (m *Material) ReleaseReservation(amount float32) {
+	if amount > m.Reserved {
+		amount = m.Reserved
+	}
+
+	if amount <= 0 {
+		return
+	}
+
+	m.TrackChange(MaterialReservationReleased{
+		MaterialUID: m.UID,
+		Amount:      amount,
+	})
+}
+
+// AvailableQuantity is the quantity that is neither consumed nor reserved.
+func (m Material) AvailableQuantity() float32 {
+	return m.Quantity.Value - m.Reserved
+}
+
+// AddLot adds a new batch of the material to the stock, so Quantity.Value grows by quantity.
+// The lot number must be unique within the material.
+func (m *Material) AddLot(lotNumber string, quantity float32, expirationDate *time.Time) error {
+	lotNumber = strings.TrimSpace(lotNumber)
+	if lotNumber == "" {
+		return errors.New("lot number cannot be empty")
+	}
+
+	for _, v := range m.Lots {
+		if v.LotNumber == lotNumber {
+			return errors.New("lot number already exists")
+		}
+	}
+
+	if quantity <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	err := validateQuantityGranularity(quantity, m.Quantity.Unit.Code)
+	if err != nil {
+		return err
+	}
+
+	m.TrackChange(MaterialLotAdded{
+		MaterialUID: m.UID,
+		Lot: MaterialLot{
+			LotNumber:      lotNumber,
+			Quantity:       quantity,
+			ExpirationDate: expirationDate,
+		},
+	})
+
+	return nil
+}
+
+// ConsumeFromLot consumes the amount from the lots in FIFO order by expiration date,
+// so the lot that expires first is used first. Lots without expiration date go last.
+// Like ConsumeQuantity, it can't consume reserved stock.
+func (m *Material) ConsumeFromLot(amount float32) error {
+	if amount <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	if amount > m.AvailableQuantity() {
+		return errors.New("insufficient available quantity")
+	}
+
+	if amount > m.lotQuantity() {
+		return errors.New("insufficient lot quantity")
+	}
+
+	m.consumeLots(amount)
+
+	return nil
+}
+
+// StockOutsideLots is the part of Quantity.Value that isn't tracked in any lot.
+func (m Material) StockOutsideLots() float32 {
+	return m.Quantity.Value - m.lotQuantity()
+}
+
+func (m *Material) lotQuantity() float32 {
+	var total float32
+	for _, v := range m.Lots {
+		total += v.Quantity
+	}
+
+	return total
+}
+
+// consumeLots consumes amount from the lots, the earliest expiration first,
+// spilling over to the next lot when one isn't enough. amount must not exceed lotQuantity.
+func (m *Material) consumeLots(amount float32) {
+	lots := make([]MaterialLot, len(m.Lots))
+	copy(lots, m.Lots)
+
+	sort.SliceStable(lots, func(i, j int) bool {
+		if lots[j].ExpirationDate == nil {
+			return lots[i].ExpirationDate != nil
+		}
+		if lots[i].ExpirationDate == nil {
+			return false
+		}
+
+		return lots[i].ExpirationDate.Before(*lots[j].ExpirationDate)
+	})
+
+	for _, v := range lots {
+		if amount <= 0 {
+			break
+		}
+
+		consumed := v.Quantity
+		if amount < consumed {
+			consumed = amount
+		}
+
+		m.TrackChange(MaterialLotConsumed{
+			MaterialUID: m.UID,
+			LotNumber:   v.LotNumber,
+			Amount:      consumed,
+		})
+
+		amount -= consumed
+	}
+}
+
+// SetLowStockThreshold sets the quantity at or below which the material is
+// considered low on stock. Passing nil removes the threshold.
+func (m *Material) SetLowStockThreshold(threshold *float32) error {
+	if threshold != nil && *threshold < 0 {
+		return errors.New("threshold cannot be negative")
+	}
 
-	case MaterialNotesChanged:
-		state.Notes = &e.Notes
+	m.TrackChange(MaterialLowStockThresholdChanged{
+		MaterialUID: m.UID,
+		Threshold:   threshold,
+	})
 
-	case MaterialProducedByChanged:
-		state.ProducedBy = &e.ProducedBy
+	return nil
+}
 
+func (m *Material) IsLowStock() bool {
+	if m.LowStockThreshold == nil {
+		return false
 	}
+
+	return m.Quantity.Value <= *m.LowStockThreshold
 }
 
-func CreateMaterial(
-	name string,
-	price string,
-	priceUnit string,
-	materialType MaterialType,
-	quantity float32,
-	quantityUnit string,
-	expirationDate *time.Time,
-	notes *string,
-	producedBy *string) (*Material, error) {
+// ChangeType reclassifies the material. Quantity units are type specific,
+// so the unit has to be valid for the new type as well, and the quantity is
+// checked against it the way ChangeQuantityUnit checks it.
+func (m *Material) ChangeType(materialType MaterialType, quantityUnit string, changedAt time.Time, changedBy uuid.UUID) error {
+	if materialType == nil {
+		return MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}
+	}
 
-	uid, err := uuid.NewV4()
+	qu, err := validateQuantityUnit(quantityUnit, materialType)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	pricePerUnit, err := CreatePricePerUnit(price, priceUnit)
+	err = m.validateQuantityChange(m.Quantity.Value, qu)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if materialType == nil {
-		return nil, errors.New("cannot be empty")
+	m.TrackChange(MaterialTypeChanged{
+		MaterialUID:  m.UID,
+		MaterialType: materialType,
+		QuantityUnit: qu,
+		ChangedAt:    changedAt,
+		ChangedBy:    changedBy,
+	})
+
+	return nil
+}
+
+// ChangePlantType changes the plant type of a PLANT material.
+// Other material types don't carry a plant type, so they are rejected.
+func (m *Material) ChangePlantType(plantType string, changedAt time.Time, changedBy uuid.UUID) error {
+	if _, ok := m.Type.(MaterialTypePlant); !ok {
+		return MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}
 	}
 
-	err = validateQuantity(quantity)
+	pt := GetPlantType(plantType)
+	if pt == (PlantType{}) {
+		return InventoryMaterialError{InventoryMaterialInvalidPlantType}
+	}
+
+	m.TrackChange(MaterialPlantTypeChanged{
+		MaterialUID: m.UID,
+		PlantType:   pt,
+		ChangedAt:   changedAt,
+		ChangedBy:   changedBy,
+	})
+
+	return nil
+}
+
+// ChangeExpirationDate sets a new expiration date, or clears it when nil.
+func (m *Material) ChangeExpirationDate(expDate *time.Time, changedAt time.Time, changedBy uuid.UUID) error {
+	err := validateExpirationDate(expDate, MaterialClock.Now())
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	qu, err := validateQuantityUnit(quantityUnit, materialType)
+	m.TrackChange(MaterialExpirationDateChanged{
+		MaterialUID:    m.UID,
+		ExpirationDate: expDate,
+		ChangedAt:      changedAt,
+		ChangedBy:      changedBy,
+	})
+
+	return nil
+}
+
+// ChangeNotes replaces the material notes. Passing nil, or notes that are
+// blank after trimming, clears them.
+func (m *Material) ChangeNotes(notes *string, changedAt time.Time, changedBy uuid.UUID) error {
+	if notes != nil {
+		trimmed := strings.TrimSpace(*notes)
+		notes = &trimmed
+
+		if trimmed == "" {
+			notes = nil
+		}
+	}
+
+	m.TrackChange(MaterialNotesChanged{
+		MaterialUID: m.UID,
+		Notes:       notes,
+		ChangedAt:   changedAt,
+		ChangedBy:   changedBy,
+	})
+
+	return nil
+}
+
+func (m *Material) ChangeProducedBy(producedBy string, changedAt time.Time, changedBy uuid.UUID) error {
+	err := validateProducedBy(producedBy)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	initial := &Material{
-		UID:          uid,
-		Name:         name,
-		PricePerUnit: pricePerUnit,
-		Type:         materialType,
-		Quantity: MaterialQuantity{
-			Value: quantity,
-			Unit:  qu,
-		},
-		ExpirationDate: expirationDate,
-		Notes:          notes,
-		ProducedBy:     producedBy,
-		CreatedDate:    time.Now(),
+	m.TrackChange(MaterialProducedByChanged{
+		MaterialUID: m.UID,
+		ProducedBy:  producedBy,
+		ChangedAt:   changedAt,
+		ChangedBy:   changedBy,
+	})
+
+	return nil
+}
+
+// ChangeSupplier sets the supplier the material is bought from, or clears it when nil.
+func (m *Material) ChangeSupplier(supplierID *uuid.UUID, changedAt time.Time, changedBy uuid.UUID) error {
+	m.TrackChange(MaterialSupplierChanged{
+		MaterialUID: m.UID,
+		SupplierID:  supplierID,
+		ChangedAt:   changedAt,
+		ChangedBy:   changedBy,
+	})
+
+	return nil
+}
+
+// Archive hides the material from the default listings while keeping its history.
+func (m *Material) Archive() error {
+	if m.Archived {
+		return errors.New("material is already archived")
 	}
 
-	initial.TrackChange(MaterialCreated{
-		UID:            initial.UID,
-		Name:           initial.Name,
-		PricePerUnit:   initial.PricePerUnit,
-		Type:           initial.Type,
-		Quantity:       initial.Quantity,
-		ExpirationDate: initial.ExpirationDate,
-		Notes:          initial.Notes,
-		ProducedBy:     initial.ProducedBy,
-		CreatedDate:    initial.CreatedDate,
+	m.TrackChange(MaterialArchived{
+		MaterialUID:  m.UID,
+		ArchivedDate: MaterialClock.Now(),
 	})
 
-	return initial, nil
+	return nil
 }
 
-func (m *Material) ChangeName(name string) error {
-	if name == "" {
-		return errors.New("cannot be empty")
+// Unarchive brings an archived material back to the default listings.
+func (m *Material) Unarchive() error {
+	if !m.Archived {
+		return errors.New("material is not archived")
+	}
+
+	m.TrackChange(MaterialUnarchived{MaterialUID: m.UID})
+
+	return nil
+}
+
+// MaterialComponent is an amount of another material that goes into a mix.
+// Quantity is in the quantity unit of that material.
+type MaterialComponent struct {
+	MaterialUID uuid.UUID `json:"material_uid"`
+	Quantity    float32   `json:"quantity"`
+}
+
+// AddComponent adds quantity of another material to the mix. A material can't contain itself
+// and each material can only be added once.
+func (m *Material) AddComponent(materialUID uuid.UUID, quantity float32) error {
+	if materialUID == m.UID {
+		return errors.New("material cannot contain itself")
+	}
+
+	if quantity <= 0 {
+		return MaterialError{Code: MaterialErrorInvalidQuantity, Field: "quantity"}
 	}
 
-	if len(name) <= 5 {
-		return errors.New("too few characters")
+	for _, v := range m.Components {
+		if v.MaterialUID == materialUID {
+			return errors.New("component already exists")
+		}
 	}
 
-	m.TrackChange(MaterialNameChanged{MaterialUID: m.UID, Name: name})
+	m.TrackChange(MaterialComponentAdded{
+		MaterialUID: m.UID,
+		Component: MaterialComponent{
+			MaterialUID: materialUID,
+			Quantity:    quantity,
+		},
+	})
 
 	return nil
 }
 
-func (m *Material) ChangePricePerUnit(price, priceUnit string) error {
-	ppu, err := CreatePricePerUnit(price, priceUnit)
-	if err != nil {
-		return err
+func (m *Material) RemoveComponent(materialUID uuid.UUID) error {
+	for _, v := range m.Components {
+		if v.MaterialUID == materialUID {
+			m.TrackChange(MaterialComponentRemoved{
+				MaterialUID:  m.UID,
+				ComponentUID: materialUID,
+			})
+
+			return nil
+		}
+	}
+
+	return errors.New("component not found")
+}
+
+// ComputeCost sums the price of every component times its quantity, using the components
+// found in materials by UID. All components must be priced in the currency of this material.
+func (m *Material) ComputeCost(materials map[uuid.UUID]Material) (Money, error) {
+	total := 0.0
+	for _, v := range m.Components {
+		component, ok := materials[v.MaterialUID]
+		if !ok {
+			return Money{}, errors.New("component material not found")
+		}
+
+		if component.PricePerUnit.CurrencyCode != m.PricePerUnit.CurrencyCode {
+			return Money{}, errors.New("component is priced in another currency")
+		}
+
+		minorUnits, err := component.PricePerUnit.MinorUnits()
+		if err != nil {
+			return Money{}, err
+		}
+
+		total += float64(minorUnits) * float64(v.Quantity)
+	}
+
+	return Money{
+		Amount:       formatAmount(total, m.PricePerUnit.CurrencyCode),
+		CurrencyCode: m.PricePerUnit.CurrencyCode,
+	}, nil
+}
+
+// Attachment references a file stored elsewhere.
+// Key is its URL or object storage key.
+type Attachment struct {
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+}
+
+// AddAttachment attaches an image, e.g. a photo of the product label, to the material.
+func (m *Material) AddAttachment(key, contentType string) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return MaterialError{Code: MaterialErrorEmptyValue, Field: "attachment"}
+	}
+
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if !strings.HasPrefix(contentType, "image/") {
+		return errors.New("attachment must be an image")
 	}
 
-	m.TrackChange(MaterialPriceChanged{MaterialUID: m.UID, Price: ppu})
+	for _, v := range m.Attachments {
+		if v.Key == key {
+			return errors.New("attachment already exists")
+		}
+	}
+
+	m.TrackChange(MaterialAttachmentAdded{
+		MaterialUID: m.UID,
+		Attachment: Attachment{
+			Key:         key,
+			ContentType: contentType,
+		},
+	})
 
 	return nil
 }
 
-func (m *Material) ChangeQuantityUnit(quantity float32, quantityUnit string, materialType MaterialType) error {
-	err := validateQuantity(quantity)
+func (m *Material) RemoveAttachment(key string) error {
+	for _, v := range m.Attachments {
+		if v.Key == key {
+			m.TrackChange(MaterialAttachmentRemoved{
+				MaterialUID: m.UID,
+				Key:         key,
+			})
+
+			return nil
+		}
+	}
+
+	return errors.New("attachment not found")
+}
+
+// normalizeTag lowercases the tag so "Organic" and "organic " are the same tag.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", MaterialError{Code: MaterialErrorEmptyValue, Field: "tag"}
+	}
+
+	return tag, nil
+}
+
+// IsPerishable tells whether the material goes bad over time, from the definition of its type.
+func (m Material) IsPerishable() bool {
+	if m.Type == nil {
+		return false
+	}
+
+	return IsPerishableType(m.Type.Code())
+}
+
+// HasTag tells whether the material is tagged with tag, ignoring case.
+func (m Material) HasTag(tag string) bool {
+	tag, err := normalizeTag(tag)
 	if err != nil {
-		return err
+		return false
 	}
 
-	qu, err := validateQuantityUnit(quantityUnit, materialType)
+	for _, v := range m.Tags {
+		if v == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddTag tags the material with a free-form tag like "organic" or "imported".
+// Adding a tag the material already has does nothing.
+func (m *Material) AddTag(tag string) error {
+	tag, err := normalizeTag(tag)
 	if err != nil {
 		return err
 	}
 
-	m.TrackChange(MaterialQuantityChanged{
+	if m.HasTag(tag) {
+		return nil
+	}
+
+	m.TrackChange(MaterialTagAdded{
 		MaterialUID: m.UID,
-		Quantity: MaterialQuantity{
-			Value: quantity,
-			Unit:  qu,
-		},
-		MaterialTypeCode: materialType.Code(),
+		Tag:         tag,
 	})
 
 	return nil
 }
 
-func (m *Material) ChangeType(materialType MaterialType) error {
-	if materialType == nil {
-		return MaterialError{MaterialErrorInvalidMaterialType}
+// RemoveTag removes the tag from the material. Removing a tag the material doesn't have does nothing.
+func (m *Material) RemoveTag(tag string) error {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
 	}
 
-	m.TrackChange(MaterialTypeChanged{
-		MaterialUID:  m.UID,
-		MaterialType: materialType,
+	if !m.HasTag(tag) {
+		return nil
+	}
+
+	m.TrackChange(MaterialTagRemoved{
+		MaterialUID: m.UID,
+		Tag:         tag,
 	})
 
 	return nil
 }
 
-func (m *Material) ChangeExpirationDate(expDate time.Time) error {
-	m.TrackChange(MaterialExpirationDateChanged{
-		MaterialUID:    m.UID,
-		ExpirationDate: expDate,
+// SetSupplierSKU records the catalog number the supplier sells the material under,
+// replacing the one it had before.
+func (m *Material) SetSupplierSKU(supplierID uuid.UUID, sku string) error {
+	if supplierID == uuid.Nil {
+		return MaterialError{Code: MaterialErrorEmptyValue, Field: "supplier_id"}
+	}
+
+	sku = strings.TrimSpace(sku)
+	if sku == "" {
+		return MaterialError{Code: MaterialErrorEmptyValue, Field: "sku"}
+	}
+
+	if current, ok := m.SupplierSKUs[supplierID]; ok && current == sku {
+		return nil
+	}
+
+	m.TrackChange(MaterialSupplierSKUSet{
+		MaterialUID: m.UID,
+		SupplierID:  supplierID,
+		SKU:         sku,
 	})
 
 	return nil
 }
 
-func (m *Material) ChangeNotes(notes string) error {
-	m.TrackChange(MaterialNotesChanged{
+// RemoveSupplierSKU forgets the catalog number of the supplier.
+// Removing the SKU of a supplier the material has none for does nothing.
+func (m *Material) RemoveSupplierSKU(supplierID uuid.UUID) error {
+	if _, ok := m.SupplierSKUs[supplierID]; !ok {
+		return nil
+	}
+
+	m.TrackChange(MaterialSupplierSKURemoved{
 		MaterialUID: m.UID,
-		Notes:       notes,
+		SupplierID:  supplierID,
 	})
 
 	return nil
 }
 
-func (m *Material) ChangeProducedBy(producedBy string) error {
-	m.TrackChange(MaterialProducedByChanged{
+func (m *Material) MarkExpired(now time.Time) error {
+	if m.ExpirationDate == nil {
+		return errors.New("material has no expiration date")
+	}
+
+	if m.IsExpired {
+		return errors.New("material is already expired")
+	}
+
+	if !now.After(*m.ExpirationDate) {
+		return errors.New("material has not expired yet")
+	}
+
+	m.TrackChange(MaterialExpired{
 		MaterialUID: m.UID,
-		ProducedBy:  producedBy,
+		ExpiredDate: now,
 	})
 
 	return nil
 }
 
+// Validate checks a loaded material against the current rules for its name, quantity
+// and quantity unit, e.g. to find records that a rule change made invalid.
+// It returns the first violation. A depleted stock of zero is valid.
+func (m *Material) Validate() error {
+	err := validateName(strings.TrimSpace(m.Name))
+	if err != nil {
+		return err
+	}
+
+	if m.Type == nil {
+		return MaterialError{Code: MaterialErrorEmptyValue, Field: "type"}
+	}
+
+	if m.Quantity.Value < 0 && !m.Quantity.IsZero() {
+		return MaterialError{Code: MaterialErrorInvalidQuantity, Field: "quantity"}
+	}
+
+	qu, err := validateQuantityUnit(m.Quantity.Unit.Code, m.Type)
+	if err != nil {
+		return err
+	}
+
+	return validateQuantityGranularity(m.Quantity.Value, qu.Code)
+}
+
+func validateName(name string) error {
+	if name == "" {
+		return MaterialError{Code: MaterialErrorEmptyValue, Field: "name"}
+	}
+
+	return nil
+}
+
+func validateProducedBy(producedBy string) error {
+	if GetProducedBySource(producedBy) == (ProducedBySource{}) {
+		return MaterialError{Code: MaterialErrorInvalidProducedBy, Field: "produced_by"}
+	}
+
+	return nil
+}
+
+func validateExpirationDate(expDate *time.Time, now time.Time) error {
+	if expDate != nil && !expDate.After(now) {
+		return MaterialError{Code: MaterialErrorExpirationDateInPast, Field: "expiration_date"}
+	}
+
+	return nil
+}
+
 func validateQuantity(quantity float32) error {
 	if quantity <= 0 {
-		return errors.New("Cannot be empty")
+		return MaterialError{Code: MaterialErrorInvalidQuantity, Field: "quantity"}
+	}
+
+	return nil
+}
+
+// wholeNumberUnits are the quantity units that can't be divided, like seeds or packets.
+// Mass and volume units can hold fractional quantities.
+var wholeNumberUnits = map[string]bool{
+	MaterialUnitSeeds:   true,
+	MaterialUnitPackets: true,
+	MaterialUnitPieces:  true,
+	MaterialUnitUnits:   true,
+}
+
+func validateQuantityGranularity(quantity float32, quantityUnit string) error {
+	if wholeNumberUnits[quantityUnit] && quantity != float32(math.Trunc(float64(quantity))) {
+		return MaterialError{Code: MaterialErrorFractionalQuantity, Field: "quantity"}
 	}
 
 	return nil
 }
 
 func validateQuantityUnit(quantityUnit string, materialType MaterialType) (MaterialQuantityUnit, error) {
-	qu := GetMaterialQuantityUnit(materialType.Code(), quantityUnit)
+	qu, ok := GetMaterialQuantityUnitE(materialType.Code(), quantityUnit)
+	if !ok {
+		return MaterialQuantityUnit{}, MaterialError{Code: MaterialErrorInvalidQuantityUnit, Field: "quantity_unit"}
+	}
+
+	// A plant only takes the unit of its packaging, so seeds in packets can't be counted
+	// in UNITS and the other way around. Without a packaging either unit would be ambiguous.
+	if mt, ok := materialType.(MaterialTypePlant); ok {
+		if mt.Packaging == PlantPackagingUnspecified {
+			return MaterialQuantityUnit{}, MaterialError{Code: MaterialErrorEmptyValue, Field: "packaging"}
+		}
 
-	if qu == (MaterialQuantityUnit{}) {
-		return MaterialQuantityUnit{}, errors.New("Cannot be empty")
+		if mt.Packaging.QuantityUnit() != qu.Code {
+			return MaterialQuantityUnit{}, MaterialError{Code: MaterialErrorInvalidQuantityUnit, Field: "quantity_unit"}
+		}
 	}
 
 	return qu, nil
@@ -0,0 +1,36 @@
+package domain
+
+// MaterialCompletenessRule is a piece of data a material is expected to have.
+// Check reports whether the material has it, Field names what is missing otherwise.
+type MaterialCompletenessRule struct {
+	Field string
+	Check func(m Material) bool
+}
+
+// MaterialCompletenessRules is the policy IsComplete checks materials against.
+// It can be replaced to expect more or less data.
+var MaterialCompletenessRules = []MaterialCompletenessRule{
+	{Field: "expiration_date", Check: hasExpirationDateIfExpected},
+	{Field: "is_expense", Check: func(m Material) bool { return m.IsExpense != nil }},
+}
+
+func hasExpirationDateIfExpected(m Material) bool {
+	return !m.IsPerishable() || m.ExpirationDate != nil
+}
+
+// MissingData lists the fields of the MaterialCompletenessRules the material fails.
+func (m Material) MissingData() []string {
+	missing := []string{}
+	for _, v := range MaterialCompletenessRules {
+		if !v.Check(m) {
+			missing = append(missing, v.Field)
+		}
+	}
+
+	return missing
+}
+
+// IsComplete tells whether the material passes every rule of MaterialCompletenessRules.
+func (m Material) IsComplete() bool {
+	return len(m.MissingData()) == 0
+}
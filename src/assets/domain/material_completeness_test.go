@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaterialCompletenessRules(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	defaultRules := MaterialCompletenessRules
+	defer func() { MaterialCompletenessRules = defaultRules }()
+
+	// When
+	missing := material.MissingData()
+
+	// Then
+	assert.Equal(t, []string{"expiration_date"}, missing)
+	assert.False(t, material.IsComplete())
+
+	// When
+	MaterialCompletenessRules = []MaterialCompletenessRule{
+		{Field: "notes", Check: func(m Material) bool { return m.Notes != nil }},
+	}
+
+	// Then
+	assert.Equal(t, []string{"notes"}, material.MissingData())
+}
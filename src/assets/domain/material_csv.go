@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaterialCSVDateLayout is the layout of the expiration date column,
+// the same one the REST API accepts.
+const MaterialCSVDateLayout = "2006-01-02"
+
+var materialCSVExportHeader = []string{
+	"uid", "name", "type", "quantity", "quantity_unit", "price_per_unit", "currency_code", "expiration_date",
+}
+
+// ExportMaterialsCSV writes a header row and one row per material
+func ExportMaterialsCSV(w io.Writer, materials []Material) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write(materialCSVExportHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range materials {
+		typeCode := ""
+		if v.Type != nil {
+			typeCode = v.Type.Code()
+		}
+
+		expirationDate := ""
+		if v.ExpirationDate != nil {
+			expirationDate = v.ExpirationDate.Format(MaterialCSVDateLayout)
+		}
+
+		err := cw.Write([]string{
+			v.UID.String(),
+			v.Name,
+			typeCode,
+			strconv.FormatFloat(float64(v.Quantity.Value), 'f', -1, 32),
+			v.Quantity.Unit.Code,
+			v.PricePerUnit.Amount,
+			v.PricePerUnit.CurrencyCode,
+			expirationDate,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// ImportError is the error of a single row of an imported CSV file.
+// Row is the line number in the file, the header being line 1.
+type ImportError struct {
+	Row int
+	Err error
+}
+
+func (e ImportError) Error() string {
+	return "row " + strconv.Itoa(e.Row) + ": " + e.Err.Error()
+}
+
+var materialCSVImportRequiredColumns = []string{
+	"name", "type", "quantity", "quantity_unit", "price_per_unit", "currency_code",
+}
+
+// ImportMaterialsCSV creates a material for every row of a CSV file with a header row.
+// Besides the required columns it reads the optional type_detail, expiration_date, notes
+// and external_id columns, and ignores the rest. A row that can't be created is reported as an ImportError
+// and doesn't stop the import. The returned error is only set when the file itself is unreadable.
+func ImportMaterialsCSV(r io.Reader) ([]*Material, []ImportError, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, v := range header {
+		columns[strings.ToLower(strings.TrimSpace(v))] = i
+	}
+
+	for _, v := range materialCSVImportRequiredColumns {
+		if _, ok := columns[v]; !ok {
+			return nil, nil, errors.New("missing column " + v)
+		}
+	}
+
+	materials := []*Material{}
+	importErrors := []ImportError{}
+
+	row := 1
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		row++
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+
+			return strings.TrimSpace(record[i])
+		}
+
+		material, err := createMaterialFromCSV(field)
+		if err != nil {
+			importErrors = append(importErrors, ImportError{Row: row, Err: err})
+			continue
+		}
+
+		materials = append(materials, material)
+	}
+
+	return materials, importErrors, nil
+}
+
+func createMaterialFromCSV(field func(name string) string) (*Material, error) {
+	mt, err := CreateMaterialTypeByCode(strings.ToUpper(field("type")), field("type_detail"))
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := strconv.ParseFloat(field("quantity"), 32)
+	if err != nil {
+		return nil, errors.New("invalid quantity")
+	}
+
+	var expDate *time.Time
+	if v := field("expiration_date"); v != "" {
+		t, err := time.Parse(MaterialCSVDateLayout, v)
+		if err != nil {
+			return nil, errors.New("invalid expiration date")
+		}
+
+		expDate = &t
+	}
+
+	var notes *string
+	if v := field("notes"); v != "" {
+		notes = &v
+	}
+
+	var externalID *string
+	if v := field("external_id"); v != "" {
+		externalID = &v
+	}
+
+	return CreateMaterial(
+		field("name"), field("price_per_unit"), strings.ToUpper(field("currency_code")), mt,
+		float32(q), strings.ToUpper(field("quantity_unit")), expDate, notes, nil, nil, nil, externalID)
+}
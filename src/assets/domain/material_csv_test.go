@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportMaterialsCSV(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	expDate := time.Now().AddDate(0, 1, 0)
+	m1, _ := CreateMaterial(`Tomato "Cherry", Red`, "2.5", MoneyEUR, mts, 12.5, MaterialUnitGram, &expDate, nil, nil, nil, nil, nil)
+	m2, _ := CreateMaterial("Kangkung", "15000", MoneyIDR, mts, 3, MaterialUnitGram, nil, nil, nil, nil, nil, nil)
+
+	buf := &bytes.Buffer{}
+
+	// When
+	err := ExportMaterialsCSV(buf, []Material{*m1, *m2})
+
+	// Then
+	assert.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "uid,name,type,quantity,quantity_unit,price_per_unit,currency_code,expiration_date", lines[0])
+	assert.Equal(t, m1.UID.String()+`,"Tomato ""Cherry"", Red",SEED,12.5,GRAM,2.50,EUR,`+expDate.Format("2006-01-02"), lines[1])
+	assert.Equal(t, m2.UID.String()+",Kangkung,SEED,3,GRAM,15000,IDR,", lines[2])
+}
+
+func TestImportMaterialsCSV(t *testing.T) {
+	// Given
+	file := `name,type,type_detail,quantity,quantity_unit,price_per_unit,currency_code,notes
+Bayam Lu Hsieh,SEED,VEGETABLE,20,PACKETS,12,EUR,From the spring order
+Green Disinfectant,AGROCHEMICAL,DISINFECTANT,5,BOTTLES,5,XYZ,
+Soft Indoor Tray,SEEDING_CONTAINER,TRAY,10,KILOGRAM,10,EUR,
+`
+
+	// When
+	materials, importErrors, err := ImportMaterialsCSV(strings.NewReader(file))
+
+	// Then
+	assert.Nil(t, err)
+
+	assert.Len(t, materials, 1)
+	assert.Equal(t, "Bayam Lu Hsieh", materials[0].Name)
+	assert.Equal(t, MaterialTypeSeedCode, materials[0].Type.Code())
+	assert.Equal(t, "From the spring order", *materials[0].Notes)
+
+	assert.Equal(t, []ImportError{
+		{Row: 3, Err: errors.New("Wrong currency code")},
+		{Row: 4, Err: MaterialError{Code: MaterialErrorInvalidQuantityUnit, Field: "quantity_unit"}},
+	}, importErrors)
+}
+
+func TestImportMaterialsCSVMissingColumn(t *testing.T) {
+	// When
+	_, _, err := ImportMaterialsCSV(strings.NewReader("name,type\nBasil,SEED\n"))
+
+	// Then
+	assert.Equal(t, errors.New("missing column quantity"), err)
+}
@@ -2,17 +2,41 @@ package domain
 
 const (
 	MaterialErrorInvalidMaterialType = iota
+	MaterialErrorInvalidProducedBy
+	MaterialErrorEmptyValue
+	MaterialErrorInvalidPrice
+	MaterialErrorInvalidQuantity
+	MaterialErrorInvalidQuantityUnit
+	MaterialErrorExpirationDateInPast
+	MaterialErrorFractionalQuantity
 )
 
-// MaterialError is a custom error from Go built-in error
+// MaterialError is a custom error from Go built-in error.
+// Field is the name of the offending input, so callers can map the error
+// to a field-specific validation message.
 type MaterialError struct {
-	Code int
+	Code  int
+	Field string
 }
 
 func (e MaterialError) Error() string {
 	switch e.Code {
 	case MaterialErrorInvalidMaterialType:
 		return "Invalid material type"
+	case MaterialErrorInvalidProducedBy:
+		return "Invalid produced by source"
+	case MaterialErrorEmptyValue:
+		return "cannot be empty"
+	case MaterialErrorInvalidPrice:
+		return "price must be a non-negative number"
+	case MaterialErrorInvalidQuantity:
+		return "quantity must be greater than zero"
+	case MaterialErrorInvalidQuantityUnit:
+		return "invalid quantity unit for material type"
+	case MaterialErrorExpirationDateInPast:
+		return "expiration date cannot be in the past"
+	case MaterialErrorFractionalQuantity:
+		return "quantity must be a whole number for this unit"
 	default:
 		return "Unrecognized Material Error Code"
 	}
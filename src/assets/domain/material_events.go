@@ -6,7 +6,12 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
+// MaterialCreatedEventVersion is the current EventVersion of MaterialCreated.
+// Version 0 events were stored before IsExpense existed.
+const MaterialCreatedEventVersion = 1
+
 type MaterialCreated struct {
+	EventVersion   int
 	UID            uuid.UUID
 	Name           string
 	PricePerUnit   PricePerUnit
@@ -15,41 +20,185 @@ type MaterialCreated struct {
 	ExpirationDate *time.Time
 	Notes          *string
 	ProducedBy     *string
+	IsExpense      *bool
+	SupplierID     *uuid.UUID
+	ExternalID     *string
 	CreatedDate    time.Time
 }
 
+// MaterialProduced follows MaterialCreated when the material is the produce of a crop.
+type MaterialProduced struct {
+	MaterialUID  uuid.UUID
+	CropUID      uuid.UUID
+	Quantity     MaterialQuantity
+	ProducedDate time.Time
+}
+
 type MaterialNameChanged struct {
 	MaterialUID uuid.UUID
 	Name        string
+	ChangedAt   time.Time
+	ChangedBy   uuid.UUID
 }
 
 type MaterialPriceChanged struct {
 	MaterialUID uuid.UUID
 	Price       PricePerUnit
+	ChangedAt   time.Time
+	ChangedBy   uuid.UUID
 }
 
 type MaterialQuantityChanged struct {
 	MaterialUID      uuid.UUID
 	MaterialTypeCode string
 	Quantity         MaterialQuantity
+	ChangedAt        time.Time
+	ChangedBy        uuid.UUID
+}
+
+type MaterialQuantityConsumed struct {
+	MaterialUID uuid.UUID
+	Amount      float32
+}
+
+type MaterialQuantityRestocked struct {
+	MaterialUID    uuid.UUID
+	Amount         float32
+	ExpirationDate *time.Time
+}
+
+type MaterialQuantityReserved struct {
+	MaterialUID uuid.UUID
+	Amount      float32
+}
+
+type MaterialReservationReleased struct {
+	MaterialUID uuid.UUID
+	Amount      float32
+}
+
+type MaterialLotAdded struct {
+	MaterialUID uuid.UUID
+	Lot         MaterialLot
+}
+
+type MaterialLotConsumed struct {
+	MaterialUID uuid.UUID
+	LotNumber   string
+	Amount      float32
+}
+
+type MaterialLowStockThresholdChanged struct {
+	MaterialUID uuid.UUID
+	Threshold   *float32
 }
 
 type MaterialTypeChanged struct {
 	MaterialUID  uuid.UUID
 	MaterialType MaterialType
+	QuantityUnit MaterialQuantityUnit
+	ChangedAt    time.Time
+	ChangedBy    uuid.UUID
+}
+
+type MaterialPlantTypeChanged struct {
+	MaterialUID uuid.UUID
+	PlantType   PlantType
+	ChangedAt   time.Time
+	ChangedBy   uuid.UUID
 }
 
 type MaterialExpirationDateChanged struct {
 	MaterialUID    uuid.UUID
-	ExpirationDate time.Time
+	ExpirationDate *time.Time
+	ChangedAt      time.Time
+	ChangedBy      uuid.UUID
 }
 
 type MaterialNotesChanged struct {
 	MaterialUID uuid.UUID
-	Notes       string
+	Notes       *string
+	ChangedAt   time.Time
+	ChangedBy   uuid.UUID
 }
 
 type MaterialProducedByChanged struct {
 	MaterialUID uuid.UUID
 	ProducedBy  string
+	ChangedAt   time.Time
+	ChangedBy   uuid.UUID
+}
+
+type MaterialSupplierChanged struct {
+	MaterialUID uuid.UUID
+	SupplierID  *uuid.UUID
+	ChangedAt   time.Time
+	ChangedBy   uuid.UUID
+}
+
+type MaterialExpired struct {
+	MaterialUID uuid.UUID
+	ExpiredDate time.Time
+}
+
+// MaterialMerged adds the quantity of an archived duplicate to the material,
+// along with its lots and its reserved quantity, which Amount already counts.
+// ExpirationDate is the earliest expiration date of both.
+type MaterialMerged struct {
+	MaterialUID       uuid.UUID
+	MergedMaterialUID uuid.UUID
+	Amount            float32
+	Lots              []MaterialLot
+	Reserved          float32
+	ExpirationDate    *time.Time
+}
+
+type MaterialArchived struct {
+	MaterialUID  uuid.UUID
+	ArchivedDate time.Time
+}
+
+type MaterialUnarchived struct {
+	MaterialUID uuid.UUID
+}
+
+type MaterialTagAdded struct {
+	MaterialUID uuid.UUID
+	Tag         string
+}
+
+type MaterialTagRemoved struct {
+	MaterialUID uuid.UUID
+	Tag         string
+}
+
+type MaterialSupplierSKUSet struct {
+	MaterialUID uuid.UUID
+	SupplierID  uuid.UUID
+	SKU         string
+}
+
+type MaterialSupplierSKURemoved struct {
+	MaterialUID uuid.UUID
+	SupplierID  uuid.UUID
+}
+
+type MaterialComponentAdded struct {
+	MaterialUID uuid.UUID
+	Component   MaterialComponent
+}
+
+type MaterialComponentRemoved struct {
+	MaterialUID  uuid.UUID
+	ComponentUID uuid.UUID
+}
+
+type MaterialAttachmentAdded struct {
+	MaterialUID uuid.UUID
+	Attachment  Attachment
+}
+
+type MaterialAttachmentRemoved struct {
+	MaterialUID uuid.UUID
+	Key         string
 }
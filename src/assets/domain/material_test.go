@@ -1,8 +1,12 @@
 package domain
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
+	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -11,88 +15,2324 @@ func TestCreateInventorySeed(t *testing.T) {
 
 	// When
 	mts, err1 := CreateMaterialTypeSeed(PlantTypeVegetable)
-	material1, err2 := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil)
+	material1, err2 := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
 	tp, ok := material1.Type.(MaterialTypeSeed)
 
 	// Then
 	assert.Nil(t, err1)
 	assert.Nil(t, err2)
 	assert.Equal(t, "Bayam Lu Hsieh", material1.Name)
-	assert.Equal(t, "12", material1.PricePerUnit.Amount)
+	assert.Equal(t, "12.00", material1.PricePerUnit.Amount)
 	assert.Equal(t, true, ok)
 	assert.Equal(t, PlantTypeVegetable, tp.PlantType.Code)
 
 	// When
 	mta, err1 := CreateMaterialTypeAgrochemical(ChemicalTypeDisinfectant)
-	material2, err2 := CreateMaterial("Green Disinfectant", "5", MoneyEUR, mta, 5, MaterialUnitPackets, nil, nil, nil)
+	material2, err2 := CreateMaterial("Green Disinfectant", "5", MoneyEUR, mta, 5, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
 	ta, ok := material2.Type.(MaterialTypeAgrochemical)
 
 	// Then
 	assert.Nil(t, err1)
 	assert.Nil(t, err2)
 	assert.Equal(t, "Green Disinfectant", material2.Name)
-	assert.Equal(t, "5", material2.PricePerUnit.Amount)
+	assert.Equal(t, "5.00", material2.PricePerUnit.Amount)
 	assert.Equal(t, true, ok)
 	assert.Equal(t, ChemicalTypeDisinfectant, ta.ChemicalType.Code)
 
 	// When
 	mtsc, err1 := CreateMaterialTypeSeedingContainer(ContainerTypeTray)
-	material3, err2 := CreateMaterial("Soft Indoor Tray Pack", "10", MoneyEUR, mtsc, 10, MaterialUnitPieces, nil, nil, nil)
+	material3, err2 := CreateMaterial("Soft Indoor Tray Pack", "10", MoneyEUR, mtsc, 10, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
 	tsc, ok := material3.Type.(MaterialTypeSeedingContainer)
 
 	// Then
 	assert.Nil(t, err1)
 	assert.Nil(t, err2)
 	assert.Equal(t, "Soft Indoor Tray Pack", material3.Name)
-	assert.Equal(t, "10", material3.PricePerUnit.Amount)
+	assert.Equal(t, "10.00", material3.PricePerUnit.Amount)
 	assert.Equal(t, true, ok)
 	assert.Equal(t, ContainerTypeTray, tsc.ContainerType.Code)
 
 	// When
 	mtgm := MaterialTypeGrowingMedium{}
-	material4, err1 := CreateMaterial("Organic Super Soil", "2", MoneyEUR, mtgm, 5, MaterialUnitBags, nil, nil, nil)
+	material4, err1 := CreateMaterial("Organic Super Soil", "2", MoneyEUR, mtgm, 5, MaterialUnitBags, nil, nil, nil, nil, nil, nil)
 	tgm, ok := material4.Type.(MaterialTypeGrowingMedium)
 
 	// Then
 	assert.Nil(t, err1)
 	assert.Equal(t, "Organic Super Soil", material4.Name)
-	assert.Equal(t, "2", material4.PricePerUnit.Amount)
+	assert.Equal(t, "2.00", material4.PricePerUnit.Amount)
 	assert.Equal(t, true, ok)
 	assert.Equal(t, MaterialTypeGrowingMediumCode, tgm.Code())
 
 	// When
 	mtl := MaterialTypeLabelAndCropSupport{}
-	material5, err1 := CreateMaterial("Clean Label", "5", MoneyEUR, mtl, 5, MaterialUnitPieces, nil, nil, nil)
+	material5, err1 := CreateMaterial("Clean Label", "5", MoneyEUR, mtl, 5, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
 	tl, ok := material5.Type.(MaterialTypeLabelAndCropSupport)
 
 	// Then
 	assert.Nil(t, err1)
 	assert.Equal(t, "Clean Label", material5.Name)
-	assert.Equal(t, "5", material5.PricePerUnit.Amount)
+	assert.Equal(t, "5.00", material5.PricePerUnit.Amount)
 	assert.Equal(t, true, ok)
 	assert.Equal(t, MaterialTypeLabelAndCropSupportCode, tl.Code())
 
 	// When
 	mtph := MaterialTypePostHarvestSupply{}
-	material6, err1 := CreateMaterial("Warm Solid Plastic", "5", MoneyEUR, mtph, 5, MaterialUnitPieces, nil, nil, nil)
+	material6, err1 := CreateMaterial("Warm Solid Plastic", "5", MoneyEUR, mtph, 5, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
 	tph, ok := material6.Type.(MaterialTypePostHarvestSupply)
 
 	// Then
 	assert.Nil(t, err1)
 	assert.Equal(t, "Warm Solid Plastic", material6.Name)
-	assert.Equal(t, "5", material6.PricePerUnit.Amount)
+	assert.Equal(t, "5.00", material6.PricePerUnit.Amount)
 	assert.Equal(t, true, ok)
 	assert.Equal(t, MaterialTypePostHarvestSupplyCode, tph.Code())
 
 	// When
 	mto := MaterialTypeOther{}
-	material7, err1 := CreateMaterial("Night Lamp Bright", "3", MoneyEUR, mto, 3, MaterialUnitPieces, nil, nil, nil)
+	material7, err1 := CreateMaterial("Night Lamp Bright", "3", MoneyEUR, mto, 3, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
 	mo, ok := material7.Type.(MaterialTypeOther)
 
 	// Then
 	assert.Nil(t, err1)
 	assert.Equal(t, "Night Lamp Bright", material7.Name)
-	assert.Equal(t, "3", material7.PricePerUnit.Amount)
+	assert.Equal(t, "3.00", material7.PricePerUnit.Amount)
 	assert.Equal(t, true, ok)
 	assert.Equal(t, MaterialTypeOtherCode, mo.Code())
 }
+
+func TestCreateMaterialWithIDR(t *testing.T) {
+	// Given
+	mtgm := MaterialTypeGrowingMedium{}
+
+	// When
+	material, err := CreateMaterial("Pupuk Kandang", "25000", MoneyIDR, mtgm, 10, MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, MoneyIDR, material.PricePerUnit.CurrencyCode)
+	assert.Equal(t, "Rp", material.PricePerUnit.Symbol())
+
+	// When
+	replayed := &Material{}
+	for _, v := range material.UncommittedChanges {
+		replayed.Transition(v)
+	}
+
+	// Then
+	assert.Equal(t, MoneyIDR, replayed.PricePerUnit.CurrencyCode)
+	assert.Equal(t, "Rp", replayed.PricePerUnit.Symbol())
+
+	// When
+	b, err1 := json.Marshal(material.PricePerUnit)
+	ppu := PricePerUnit{}
+	err2 := json.Unmarshal(b, &ppu)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, material.PricePerUnit, ppu)
+	assert.Equal(t, "Rp", ppu.Symbol())
+}
+
+func TestChangePricePerUnitWithUSD(t *testing.T) {
+	// Given
+	mto := MaterialTypeOther{}
+	material, err := CreateMaterial("Shade Net Roll", "15", MoneyUSD, mto, 4, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.ChangePricePerUnit("17", MoneyUSD, time.Now(), uuid.Nil)
+	event, ok := material.UncommittedChanges[len(material.UncommittedChanges)-1].(MaterialPriceChanged)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, err1)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, MoneyUSD, event.Price.CurrencyCode)
+	assert.Equal(t, "17.00", event.Price.Amount)
+	assert.Equal(t, MoneyUSD, material.PricePerUnit.CurrencyCode)
+	assert.Equal(t, "$", material.PricePerUnit.Symbol())
+}
+
+func TestValidatePriceAmount(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	var tests = []struct {
+		amount   string
+		expected error
+	}{
+		{"12.50", nil},
+		{"0", nil},
+		{"-3", MaterialError{Code: MaterialErrorInvalidPrice, Field: "price_per_unit"}},
+		{"", MaterialError{Code: MaterialErrorInvalidPrice, Field: "price_per_unit"}},
+		{"abc", MaterialError{Code: MaterialErrorInvalidPrice, Field: "price_per_unit"}},
+	}
+
+	for _, test := range tests {
+		// When
+		_, actual := CreatePricePerUnit(test.amount, MoneyEUR)
+
+		// Then
+		assert.Equal(t, test.expected, actual, "amount %q", test.amount)
+	}
+
+	assert.Equal(t, "price must be a non-negative number", MaterialError{Code: MaterialErrorInvalidPrice}.Error())
+}
+
+func TestPricePerUnitAmountFloat(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	var tests = []struct {
+		amount      string
+		expected    float64
+		expectedErr error
+	}{
+		{"12.50", 12.5, nil},
+		{"0", 0, nil},
+		{"1000000", 1000000, nil},
+		{"", 0, errors.New("invalid price amount")},
+		{"12,50", 0, errors.New("invalid price amount")},
+	}
+
+	for _, test := range tests {
+		// When
+		actual, err := PricePerUnit{Amount: test.amount, CurrencyCode: MoneyEUR}.AmountFloat()
+
+		// Then
+		assert.Equal(t, test.expectedErr, err, "amount %q", test.amount)
+		assert.Equal(t, test.expected, actual, "amount %q", test.amount)
+	}
+}
+
+func TestChangePricePerUnitCurrencyMismatch(t *testing.T) {
+	// Given
+	mtl := MaterialTypeLabelAndCropSupport{}
+	material, err := CreateMaterial("Bamboo Stake", "2", MoneyEUR, mtl, 50, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.ChangePricePerUnit("3", MoneyEUR, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, err1)
+	assert.Equal(t, "3.00", material.PricePerUnit.Amount)
+
+	// When
+	err2 := material.ChangePricePerUnit("45000", MoneyIDR, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Equal(t, errors.New("cannot change currency of existing material"), err2)
+	assert.Equal(t, MoneyEUR, material.PricePerUnit.CurrencyCode)
+	assert.Equal(t, "3.00", material.PricePerUnit.Amount)
+	assert.Len(t, material.UncommittedChanges, 2)
+
+	// When
+	err3 := material.ChangePriceCurrency("45000", MoneyIDR, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err3)
+	assert.Equal(t, MoneyIDR, material.PricePerUnit.CurrencyCode)
+	assert.Equal(t, "45000", material.PricePerUnit.Amount)
+}
+
+func TestMarkMaterialExpired(t *testing.T) {
+	// Given
+	expDate := time.Now().AddDate(0, 1, 0)
+	mts, _ := CreateMaterialTypeSeed(PlantTypeHerb)
+	material, err := CreateMaterial("Basil Genovese", "4", MoneyEUR, mts, 10, MaterialUnitPackets, &expDate, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.MarkExpired(expDate.AddDate(0, 0, -1))
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, errors.New("material has not expired yet"), err1)
+	assert.Equal(t, false, material.IsExpired)
+
+	// When
+	err2 := material.MarkExpired(expDate.AddDate(0, 0, 1))
+
+	// Then
+	assert.Nil(t, err2)
+	assert.Equal(t, true, material.IsExpired)
+	assert.IsType(t, MaterialExpired{}, material.UncommittedChanges[len(material.UncommittedChanges)-1])
+
+	// When
+	err3 := material.MarkExpired(expDate.AddDate(0, 0, 2))
+
+	// Then
+	assert.Equal(t, errors.New("material is already expired"), err3)
+	assert.Len(t, material.UncommittedChanges, 2)
+
+	// Given
+	material2, err := CreateMaterial("Basil Thai", "4", MoneyEUR, mts, 10, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err4 := material2.MarkExpired(expDate)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, errors.New("material has no expiration date"), err4)
+}
+
+func TestChangeMaterialNotes(t *testing.T) {
+	// Given
+	mtgm := MaterialTypeGrowingMedium{}
+	material, err := CreateMaterial("Coco Peat Block", "3", MoneyEUR, mtgm, 20, MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+	notes1 := "  Keep dry  "
+	notes2 := "Stored in shed B"
+
+	// When
+	err1 := material.ChangeNotes(&notes1, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, err1)
+	assert.Equal(t, "Keep dry", *material.Notes)
+	assert.Len(t, material.UncommittedChanges, 2)
+	assert.Equal(t, 2, material.Version)
+
+	// When
+	err2 := material.ChangeNotes(&notes2, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err2)
+	assert.Equal(t, "Stored in shed B", *material.Notes)
+	assert.Len(t, material.UncommittedChanges, 3)
+	assert.Equal(t, 3, material.Version)
+
+	// When
+	err3 := material.ChangeNotes(nil, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err3)
+	assert.Nil(t, material.Notes)
+	assert.Len(t, material.UncommittedChanges, 4)
+	assert.Equal(t, 4, material.Version)
+}
+
+func TestChangeMaterialExpirationDate(t *testing.T) {
+	// Given
+	mta, _ := CreateMaterialTypeAgrochemical(ChemicalTypeFertilizer)
+	material, err := CreateMaterial("Liquid Seaweed", "8", MoneyEUR, mta, 6, MaterialUnitBottles, nil, nil, nil, nil, nil, nil)
+	future := time.Now().AddDate(1, 0, 0)
+	past := time.Now().AddDate(0, 0, -1)
+
+	// When
+	err1 := material.ChangeExpirationDate(&future, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, err1)
+	assert.Equal(t, future, *material.ExpirationDate)
+
+	// When
+	err2 := material.ChangeExpirationDate(&past, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Equal(t, MaterialError{Code: MaterialErrorExpirationDateInPast, Field: "expiration_date"}, err2)
+	assert.Equal(t, future, *material.ExpirationDate)
+
+	// When
+	err3 := material.ChangeExpirationDate(nil, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err3)
+	assert.Nil(t, material.ExpirationDate)
+}
+
+func TestChangeMaterialName(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	var tests = []struct {
+		name         string
+		expected     error
+		expectedName string
+	}{
+		{"NPK", nil, "NPK"},
+		{" Urea ", nil, "Urea"},
+		{" ", MaterialError{Code: MaterialErrorEmptyValue, Field: "name"}, "Fertilizer Mix"},
+		{"", MaterialError{Code: MaterialErrorEmptyValue, Field: "name"}, "Fertilizer Mix"},
+	}
+
+	for _, test := range tests {
+		mta, _ := CreateMaterialTypeAgrochemical(ChemicalTypeFertilizer)
+		material, _ := CreateMaterial("Fertilizer Mix", "5", MoneyEUR, mta, 2, MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+
+		// When
+		err := material.ChangeName(test.name, time.Now(), uuid.Nil)
+
+		// Then
+		assert.Equal(t, test.expected, err, "name %q", test.name)
+		assert.Equal(t, test.expectedName, material.Name, "name %q", test.name)
+	}
+}
+
+func TestCreateMaterialNameValidation(t *testing.T) {
+	// Given
+	mto := MaterialTypeOther{}
+	existing, _ := CreateMaterial("Garden Hose", "10", MoneyEUR, mto, 1, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
+
+	// When
+	material, err1 := CreateMaterial("", "10", MoneyEUR, mto, 1, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
+	err2 := existing.ChangeName("", time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, material)
+	assert.NotNil(t, err1)
+	assert.Equal(t, err2, err1)
+
+	// When
+	material, err := CreateMaterial("  Lime ", "10", MoneyEUR, mto, 1, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, "Lime", material.Name)
+}
+
+func TestChangeMaterialType(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	mtp, _ := CreateMaterialTypePlantWithPackaging(PlantTypeVegetable, string(PlantPackagingIndividual))
+	material, err := CreateMaterial("Tomato Cherry", "1", MoneyEUR, mts, 30, MaterialUnitSeeds, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.ChangeType(mtp, MaterialUnitSeeds, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err)
+	assert.NotNil(t, err1)
+	assert.Equal(t, MaterialTypeSeedCode, material.Type.Code())
+	assert.Equal(t, MaterialUnitSeeds, material.Quantity.Unit.Code)
+
+	// When
+	err2 := material.ChangeType(mtp, MaterialUnitUnits, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err2)
+	assert.Equal(t, MaterialTypePlantCode, material.Type.Code())
+	assert.Equal(t, MaterialUnitUnits, material.Quantity.Unit.Code)
+	assert.Equal(t, float32(30), material.Quantity.Value)
+}
+
+func TestChangeMaterialTypeChecksTheQuantity(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	mtp, _ := CreateMaterialTypePlantWithPackaging(PlantTypeVegetable, string(PlantPackagingIndividual))
+
+	fractional, _ := CreateMaterial("Tomato Cherry", "1", MoneyEUR, mts, 2.5, MaterialUnitKilogram, nil, nil, nil, nil, nil, nil)
+	reserved, _ := CreateMaterial("Tomato Cherry", "1", MoneyEUR, mts, 30, MaterialUnitSeeds, nil, nil, nil, nil, nil, nil)
+	reserved.Reserve(10)
+	lots, _ := CreateMaterial("Tomato Cherry", "1", MoneyEUR, mts, 30, MaterialUnitSeeds, nil, nil, nil, nil, nil, nil)
+	lots.AddLot("LOT-A", 10, nil)
+
+	// When
+	err1 := fractional.ChangeType(mtp, MaterialUnitUnits, time.Now(), uuid.Nil)
+	err2 := reserved.ChangeType(mtp, MaterialUnitUnits, time.Now(), uuid.Nil)
+	err3 := lots.ChangeType(mtp, MaterialUnitUnits, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Equal(t, MaterialError{Code: MaterialErrorFractionalQuantity, Field: "quantity"}, err1)
+	assert.Equal(t, errors.New("cannot change the quantity unit of a material with reservations"), err2)
+	assert.Equal(t, errors.New("cannot change the quantity unit of a material with lots"), err3)
+
+	for _, v := range []*Material{fractional, reserved, lots} {
+		assert.Equal(t, MaterialTypeSeedCode, v.Type.Code())
+	}
+}
+
+func TestNewMaterialFromHistory(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeFruit)
+	material, _ := CreateMaterial("Strawberry Albion", "6", MoneyEUR, mts, 3, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.ChangeName("Strawberry Seascape", time.Now(), uuid.Nil)
+
+	// When
+	replayed := NewMaterialFromHistory(material.UncommittedChanges)
+
+	// Then
+	assert.Equal(t, material.UID, replayed.UID)
+	assert.Equal(t, "Strawberry Seascape", replayed.Name)
+	assert.Equal(t, material.PricePerUnit, replayed.PricePerUnit)
+	assert.Equal(t, material.Quantity, replayed.Quantity)
+	assert.Equal(t, 2, replayed.Version)
+	assert.Empty(t, replayed.UncommittedChanges)
+}
+
+func TestMaterialVersion(t *testing.T) {
+	// Given
+	mto := MaterialTypeOther{}
+
+	// When
+	material, _ := CreateMaterial("Pruning Shears", "12", MoneyEUR, mto, 2, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
+
+	// Then
+	assert.Equal(t, 1, material.Version)
+	assert.Equal(t, 0, material.BaseVersion())
+
+	// When
+	material.ChangeName("Pruning Shears XL", time.Now(), uuid.Nil)
+	material.ChangePricePerUnit("14", MoneyEUR, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Equal(t, 3, material.Version)
+	assert.Equal(t, 0, material.BaseVersion())
+
+	// When
+	replayed := NewMaterialFromHistory(material.UncommittedChanges)
+	replayed.ChangeName("Hand Pruner", time.Now(), uuid.Nil)
+
+	// Then
+	assert.Equal(t, 4, replayed.Version)
+	assert.Equal(t, 3, replayed.BaseVersion())
+}
+
+func TestMaterialChangesUpdateState(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Spinach Bloomsdale", "3", MoneyEUR, mts, 10, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	expDate := time.Now().AddDate(0, 6, 0)
+	notes := "Sow in early spring"
+
+	// When
+	material.ChangeName("Spinach Giant Winter", time.Now(), uuid.Nil)
+	material.ChangePricePerUnit("3.5", MoneyEUR, time.Now(), uuid.Nil)
+	material.ChangeQuantityUnit(250, MaterialUnitGram, mts, time.Now(), uuid.Nil)
+	material.ChangeExpirationDate(&expDate, time.Now(), uuid.Nil)
+	material.ChangeNotes(&notes, time.Now(), uuid.Nil)
+	material.ChangeProducedBy(ProducedBySupplier, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Equal(t, "Spinach Giant Winter", material.Name)
+	assert.Equal(t, "3.50", material.PricePerUnit.Amount)
+	assert.Equal(t, float32(250), material.Quantity.Value)
+	assert.Equal(t, MaterialUnitGram, material.Quantity.Unit.Code)
+	assert.Equal(t, expDate, *material.ExpirationDate)
+	assert.Equal(t, notes, *material.Notes)
+	assert.Equal(t, ProducedBySupplier, *material.ProducedBy)
+	assert.Equal(t, 7, material.Version)
+}
+
+func TestChangeMaterialPlantType(t *testing.T) {
+	// Given
+	mtp, _ := CreateMaterialTypePlantWithPackaging(PlantTypeVegetable, string(PlantPackagingIndividual))
+	plant, _ := CreateMaterial("Chili Seedling", "1", MoneyEUR, mtp, 40, MaterialUnitUnits, nil, nil, nil, nil, nil, nil)
+
+	mta, _ := CreateMaterialTypeAgrochemical(ChemicalTypePesticide)
+	chemical, _ := CreateMaterial("Neem Oil", "9", MoneyEUR, mta, 3, MaterialUnitBottles, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := plant.ChangePlantType(PlantTypeFruit, time.Now(), uuid.Nil)
+	tp, ok := plant.Type.(MaterialTypePlant)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, PlantTypeFruit, tp.PlantType.Code)
+
+	// When
+	err2 := plant.ChangePlantType("MUSHROOM", time.Now(), uuid.Nil)
+
+	// Then
+	assert.Equal(t, InventoryMaterialError{InventoryMaterialInvalidPlantType}, err2)
+
+	// When
+	err3 := chemical.ChangePlantType(PlantTypeHerb, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}, err3)
+	assert.Equal(t, MaterialTypeAgrochemicalCode, chemical.Type.Code())
+}
+
+func TestConsumeMaterialQuantity(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Kale Lacinato", "2", MoneyEUR, mts, 10, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.ConsumeQuantity(4)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, float32(6), material.Quantity.Value)
+
+	// When
+	err2 := material.ConsumeQuantity(7)
+
+	// Then
+	assert.Equal(t, errors.New("insufficient quantity"), err2)
+	assert.Equal(t, float32(6), material.Quantity.Value)
+
+	// When
+	err3 := material.ConsumeQuantity(0)
+
+	// Then
+	assert.Equal(t, errors.New("amount must be greater than zero"), err3)
+
+	// When
+	err4 := material.ConsumeQuantity(6)
+
+	// Then
+	assert.Nil(t, err4)
+	assert.Equal(t, float32(0), material.Quantity.Value)
+	assert.IsType(t, MaterialQuantityConsumed{}, material.UncommittedChanges[len(material.UncommittedChanges)-1])
+}
+
+func TestConsumeQuantityKeepsReservedStock(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Kale Lacinato", "2", MoneyEUR, mts, 10, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.Reserve(6)
+
+	// When
+	err1 := material.ConsumeQuantity(5)
+	err2 := material.ConsumeQuantityIn(5, MaterialUnitPackets)
+	_, err3 := material.Split(5)
+	err4 := material.ChangeQuantityUnit(5, MaterialUnitPackets, mts, time.Now(), uuid.Nil)
+	err5 := material.ConsumeQuantity(4)
+
+	// Then
+	assert.Equal(t, errors.New("insufficient available quantity"), err1)
+	assert.Equal(t, errors.New("insufficient available quantity"), err2)
+	assert.Equal(t, errors.New("insufficient available quantity"), err3)
+	assert.Equal(t, errors.New("insufficient available quantity"), err4)
+	assert.Nil(t, err5)
+	assert.Equal(t, float32(6), material.Quantity.Value)
+	assert.Equal(t, float32(6), material.Reserved)
+}
+
+func TestRestockMaterialQuantity(t *testing.T) {
+	// Given
+	mtgm := MaterialTypeGrowingMedium{}
+	material, _ := CreateMaterial("Perlite Fine", "7", MoneyEUR, mtgm, 3, MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+	expDate := time.Now().AddDate(2, 0, 0)
+
+	// When
+	err1 := material.RestockQuantity(5, nil)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, float32(8), material.Quantity.Value)
+	assert.Nil(t, material.ExpirationDate)
+
+	// When
+	err2 := material.RestockQuantity(2, &expDate)
+
+	// Then
+	assert.Nil(t, err2)
+	assert.Equal(t, float32(10), material.Quantity.Value)
+	assert.Equal(t, expDate, *material.ExpirationDate)
+
+	// When
+	err3 := material.RestockQuantity(0, nil)
+	err4 := material.RestockQuantity(-1, nil)
+
+	// Then
+	assert.Equal(t, errors.New("amount must be greater than zero"), err3)
+	assert.Equal(t, errors.New("amount must be greater than zero"), err4)
+	assert.Equal(t, float32(10), material.Quantity.Value)
+}
+
+func TestMaterialLowStock(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	threshold := float32(5)
+	var tests = []struct {
+		quantity  float32
+		threshold *float32
+		expected  bool
+	}{
+		{10, &threshold, false},
+		{5, &threshold, true},
+		{2, &threshold, true},
+		{2, nil, false},
+	}
+
+	for _, test := range tests {
+		mto := MaterialTypeOther{}
+		material, _ := CreateMaterial("Plant Clips", "1", MoneyEUR, mto, test.quantity, MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
+
+		// When
+		err := material.SetLowStockThreshold(test.threshold)
+		replayed := NewMaterialFromHistory(material.UncommittedChanges)
+
+		// Then
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, material.IsLowStock(), "quantity %v", test.quantity)
+		assert.Equal(t, test.expected, replayed.IsLowStock(), "quantity %v", test.quantity)
+	}
+}
+
+func TestFindMaterialQuantityUnits(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	var tests = []struct {
+		typeCode      string
+		expectedFound bool
+		expectedUnit  string
+	}{
+		{MaterialTypeSeedCode, true, MaterialUnitSeeds},
+		{MaterialTypePlantCode, true, MaterialUnitUnits},
+		{MaterialTypeGrowingMediumCode, true, MaterialUnitBags},
+		{MaterialTypeAgrochemicalCode, true, MaterialUnitPackets},
+		{MaterialTypeLabelAndCropSupportCode, true, MaterialUnitPieces},
+		{MaterialTypeSeedingContainerCode, true, MaterialUnitPieces},
+		{MaterialTypePostHarvestSupplyCode, true, MaterialUnitPieces},
+		{MaterialTypeOtherCode, true, MaterialUnitPieces},
+		{"BOGUS", false, ""},
+	}
+
+	for _, test := range tests {
+		// When
+		units, found := FindMaterialQuantityUnits(test.typeCode)
+
+		// Then
+		assert.Equal(t, test.expectedFound, found, "type %q", test.typeCode)
+		if test.expectedFound {
+			assert.Equal(t, test.expectedUnit, units[0].Code, "type %q", test.typeCode)
+		} else {
+			assert.Nil(t, units)
+		}
+	}
+}
+
+func TestMaterialQuantityUnitsE(t *testing.T) {
+	// When
+	units, err1 := MaterialQuantityUnitsE(MaterialTypeSeedCode)
+	bogus, err2 := MaterialQuantityUnitsE("BOGUS")
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, MaterialQuantityUnits(MaterialTypeSeedCode), units)
+	assert.Nil(t, bogus)
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}, err2)
+}
+
+func TestDefaultQuantityUnit(t *testing.T) {
+	for _, v := range ListMaterialTypes() {
+		// When
+		unit, found := DefaultQuantityUnit(v.Code)
+
+		// Then
+		assert.True(t, found, v.Code)
+		assert.Contains(t, MaterialQuantityUnits(v.Code), unit, v.Code)
+	}
+
+	seed, _ := DefaultQuantityUnit(MaterialTypeSeedCode)
+	growingMedium, _ := DefaultQuantityUnit(MaterialTypeGrowingMediumCode)
+	_, found := DefaultQuantityUnit("BOGUS")
+
+	assert.Equal(t, MaterialUnitSeeds, seed.Code)
+	assert.Equal(t, MaterialUnitBags, growingMedium.Code)
+	assert.False(t, found)
+}
+
+func TestLocalizedMaterialQuantityUnits(t *testing.T) {
+	// When
+	indonesian, found1 := LocalizedMaterialQuantityUnits(MaterialTypeSeedCode, "id")
+	unknown, found2 := LocalizedMaterialQuantityUnits(MaterialTypeSeedCode, "xx")
+	english, _ := FindMaterialQuantityUnits(MaterialTypeSeedCode)
+	_, found3 := LocalizedMaterialQuantityUnits("BOGUS", "id")
+	withRegion, _ := LocalizedMaterialQuantityUnits(MaterialTypeSeedCode, "id-ID")
+	withUnderscore, _ := LocalizedMaterialQuantityUnits(MaterialTypeSeedCode, "id_ID")
+
+	// Then
+	assert.True(t, found1)
+	assert.Equal(t, MaterialQuantityUnit{Code: MaterialUnitSeeds, Label: "Benih"}, indonesian[0])
+	assert.Equal(t, MaterialQuantityUnit{Code: MaterialUnitPackets, Label: "Bungkus"}, indonesian[1])
+	assert.True(t, found2)
+	assert.Equal(t, english, unknown)
+	assert.Equal(t, "Seeds", MaterialQuantityUnits(MaterialTypeSeedCode)[0].Label)
+	assert.False(t, found3)
+	assert.Equal(t, indonesian, withRegion)
+	assert.Equal(t, indonesian, withUnderscore)
+}
+
+func TestGetMaterialQuantityUnitE(t *testing.T) {
+	// When
+	qu1, ok1 := GetMaterialQuantityUnitE(MaterialTypeSeedCode, MaterialUnitGram)
+	qu2, ok2 := GetMaterialQuantityUnitE(MaterialTypeSeedCode, MaterialUnitBottles)
+	qu3, ok3 := GetMaterialQuantityUnitE("BOGUS", MaterialUnitGram)
+
+	// Then
+	assert.Equal(t, true, ok1)
+	assert.Equal(t, MaterialQuantityUnit{Code: MaterialUnitGram, Label: "Gram"}, qu1)
+	assert.Equal(t, false, ok2)
+	assert.Equal(t, MaterialQuantityUnit{}, qu2)
+	assert.Equal(t, false, ok3)
+	assert.Equal(t, MaterialQuantityUnit{}, qu3)
+}
+
+func TestMaterialPricePerBaseUnit(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	seeds, _ := CreateMaterial("Carrot Nantes", "10.00", MoneyEUR, mts, 2, MaterialUnitKilogram, nil, nil, nil, nil, nil, nil)
+
+	mtgm := MaterialTypeGrowingMedium{}
+	soil, _ := CreateMaterial("Potting Soil", "4", MoneyEUR, mtgm, 5, MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+
+	// When
+	price, unit, err1 := seeds.PricePerBaseUnit()
+	_, _, err2 := soil.PricePerBaseUnit()
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, 0.01, price)
+	assert.Equal(t, MaterialUnitGram, unit)
+	assert.Equal(t, errors.New("quantity unit has no base unit"), err2)
+}
+
+func TestConvertQuantity(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		value         float32
+		from          string
+		to            string
+		expected      float32
+		expectedError error
+	}{
+		{2, MaterialUnitKilogram, MaterialUnitGram, 2000, nil},
+		{500, MaterialUnitGram, MaterialUnitKilogram, 0.5, nil},
+		{10, MaterialUnitGram, MaterialUnitPieces, 0, errors.New("cannot convert GRAM to PIECES")},
+		{1, MaterialUnitPackets, MaterialUnitGram, 0, errors.New("cannot convert from PACKETS")},
+	}
+
+	for _, test := range tests {
+		// When
+		result, err := ConvertQuantity(test.value, test.from, test.to)
+
+		// Then
+		assert.Equal(t, test.expectedError, err)
+		assert.Equal(t, test.expected, result)
+	}
+}
+
+func TestPricePerUnitJSONRoundTrip(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12.50", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	data, err1 := json.Marshal(material.PricePerUnit)
+
+	var ppu PricePerUnit
+	err2 := json.Unmarshal(data, &ppu)
+
+	var stored PricePerUnit
+	err3 := json.Unmarshal([]byte(`{"code":"IDR","symbol":"Rp","amount":"1000.50"}`), &stored)
+
+	_, err4 := CreatePricePerUnit("1", "XYZ")
+
+	// Then
+	assert.Nil(t, err1)
+	assert.JSONEq(t, `{"code":"EUR","symbol":"€","amount":"12.50"}`, string(data))
+	assert.Nil(t, err2)
+	assert.Equal(t, material.PricePerUnit, ppu)
+	assert.Nil(t, err3)
+	assert.Equal(t, PricePerUnit{Amount: "1000.50", CurrencyCode: MoneyIDR}, stored)
+	assert.Equal(t, errors.New("Wrong currency code"), err4)
+}
+
+func TestMaterialLots(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Tomato Cherry", "5", MoneyEUR, mts, 10, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	later := time.Now().AddDate(0, 6, 0)
+	sooner := time.Now().AddDate(0, 1, 0)
+
+	// When
+	err1 := material.AddLot("LOT-B", 5, &later)
+	err2 := material.AddLot("LOT-A", 3, &sooner)
+	err3 := material.AddLot("LOT-A", 1, nil)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, errors.New("lot number already exists"), err3)
+	assert.Len(t, material.Lots, 2)
+	assert.Equal(t, float32(18), material.Quantity.Value)
+
+	// When
+	err := material.ConsumeFromLot(4)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []MaterialLot{{LotNumber: "LOT-B", Quantity: 4, ExpirationDate: &later}}, material.Lots)
+	assert.Equal(t, float32(14), material.Quantity.Value)
+
+	n := len(material.UncommittedChanges)
+	assert.Equal(t, MaterialLotConsumed{MaterialUID: material.UID, LotNumber: "LOT-A", Amount: 3}, material.UncommittedChanges[n-2])
+	assert.Equal(t, MaterialLotConsumed{MaterialUID: material.UID, LotNumber: "LOT-B", Amount: 1}, material.UncommittedChanges[n-1])
+
+	// When
+	err = material.ConsumeFromLot(5)
+
+	// Then
+	assert.Equal(t, errors.New("insufficient lot quantity"), err)
+}
+
+func TestMaterialConsumeFromLotKeepsReservations(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Tomato Cherry", "5", MoneyEUR, mts, 1, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.AddLot("LOT-A", 5, nil)
+	material.Reserve(4)
+
+	// When
+	err1 := material.ConsumeFromLot(3)
+	err2 := material.ConsumeFromLot(2)
+
+	// Then
+	assert.Equal(t, errors.New("insufficient available quantity"), err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, float32(4), material.Quantity.Value)
+	assert.Equal(t, float32(4), material.Reserved)
+	assert.Equal(t, float32(3), material.Lots[0].Quantity)
+}
+
+func TestMaterialLotsStockOutsideLots(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Tomato Cherry", "5", MoneyEUR, mts, 10, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.AddLot("LOT-A", 5, nil)
+
+	// When
+	err1 := material.ChangeQuantityUnit(3, MaterialUnitPackets, mts, time.Now(), uuid.Nil)
+	err2 := material.ChangeQuantityUnit(15, MaterialUnitKilogram, mts, time.Now(), uuid.Nil)
+	err3 := material.ConsumeQuantity(7)
+
+	// Then
+	assert.Equal(t, errors.New("quantity cannot be less than the quantity in lots"), err1)
+	assert.Equal(t, errors.New("cannot change the quantity unit of a material with lots"), err2)
+	assert.Nil(t, err3)
+	assert.Empty(t, material.Lots)
+	assert.Equal(t, float32(8), material.Quantity.Value)
+	assert.Equal(t, float32(8), material.StockOutsideLots())
+
+	// When
+	replayed := NewMaterialFromHistory([]interface{}{
+		material.UncommittedChanges[0],
+		MaterialLotAdded{MaterialUID: material.UID, Lot: MaterialLot{LotNumber: "LOT-A", Quantity: 5}},
+		MaterialQuantityChanged{MaterialUID: material.UID, Quantity: MaterialQuantity{Value: 3, Unit: material.Quantity.Unit}},
+	})
+
+	// Then
+	assert.Equal(t, float32(5), replayed.Quantity.Value)
+	assert.Equal(t, float32(0), replayed.StockOutsideLots())
+}
+
+func TestCreateMaterialDefaultIsExpense(t *testing.T) {
+	t.Parallel()
+
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	mta, _ := CreateMaterialTypeAgrochemical(ChemicalTypeFertilizer)
+	mtp, _ := CreateMaterialTypePlantWithPackaging(PlantTypeHerb, string(PlantPackagingIndividual))
+
+	var tests = []struct {
+		materialType MaterialType
+		quantityUnit string
+		expected     bool
+	}{
+		{mts, MaterialUnitPackets, true},
+		{mta, MaterialUnitBottles, true},
+		{MaterialTypeGrowingMedium{}, MaterialUnitBags, true},
+		{mtp, MaterialUnitUnits, false},
+	}
+
+	for _, test := range tests {
+		// When
+		material, err := CreateMaterial("Some Material", "2", MoneyEUR, test.materialType, 1, test.quantityUnit, nil, nil, nil, nil, nil, nil)
+
+		// Then
+		assert.Nil(t, err)
+		assert.NotNil(t, material.IsExpense)
+		assert.Equal(t, test.expected, *material.IsExpense)
+
+		event, ok := material.UncommittedChanges[0].(MaterialCreated)
+		assert.True(t, ok)
+		assert.Equal(t, material.IsExpense, event.IsExpense)
+	}
+
+	// When
+	isExpense := true
+	material, _ := CreateMaterial("Mint", "2", MoneyEUR, mtp, 1, MaterialUnitUnits, nil, nil, nil, &isExpense, nil, nil)
+
+	// Then
+	assert.True(t, *material.IsExpense)
+}
+
+func TestMaterialClone(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	expDate := time.Now().AddDate(0, 3, 0)
+	notes := "Keep dry"
+	material, _ := CreateMaterial("Chili Rawit", "3", MoneyEUR, mts, 10, MaterialUnitPackets, &expDate, &notes, nil, nil, nil, nil)
+	material.AddLot("LOT-1", 2, &expDate)
+
+	// When
+	clone := material.Clone()
+
+	*clone.ExpirationDate = clone.ExpirationDate.AddDate(1, 0, 0)
+	*clone.Notes = "Changed"
+	*clone.IsExpense = false
+	*clone.Lots[0].ExpirationDate = clone.Lots[0].ExpirationDate.AddDate(1, 0, 0)
+	clone.ChangeName("Chili Merah", time.Now(), uuid.Nil)
+
+	// Then
+	assert.Equal(t, expDate, *material.ExpirationDate)
+	assert.Equal(t, expDate, *material.Lots[0].ExpirationDate)
+	assert.Equal(t, "Keep dry", *material.Notes)
+	assert.True(t, *material.IsExpense)
+	assert.Equal(t, "Chili Rawit", material.Name)
+	assert.Len(t, material.UncommittedChanges, 2)
+
+	assert.Equal(t, "Chili Merah", clone.Name)
+	assert.Len(t, clone.UncommittedChanges, 1)
+	assert.Equal(t, material.Version+1, clone.Version)
+}
+
+func TestMaterialMarkChangesCommitted(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Kangkung", "2", MoneyEUR, mts, 10, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.ChangeName("Kangkung Bangkok", time.Now(), uuid.Nil)
+
+	// When
+	material.MarkChangesCommitted()
+
+	// Then
+	assert.Empty(t, material.UncommittedChanges)
+	assert.Equal(t, "Kangkung Bangkok", material.Name)
+	assert.Equal(t, 2, material.Version)
+	assert.Equal(t, 2, material.BaseVersion())
+}
+
+func TestCreateMaterialExpirationDate(t *testing.T) {
+	t.Parallel()
+
+	future := time.Now().AddDate(0, 1, 0)
+	past := time.Now().AddDate(0, 0, -1)
+
+	var tests = []struct {
+		expirationDate *time.Time
+		expectedError  error
+	}{
+		{&future, nil},
+		{&past, MaterialError{Code: MaterialErrorExpirationDateInPast, Field: "expiration_date"}},
+		{nil, nil},
+	}
+
+	for _, test := range tests {
+		// Given
+		mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+
+		// When
+		_, err := CreateMaterial("Pakcoy", "2", MoneyEUR, mts, 10, MaterialUnitPackets, test.expirationDate, nil, nil, nil, nil, nil)
+
+		// Then
+		assert.Equal(t, test.expectedError, err)
+	}
+}
+
+func TestMaterialProducedBy(t *testing.T) {
+	// Given
+	mtp, _ := CreateMaterialTypePlantWithPackaging(PlantTypeHerb, string(PlantPackagingIndividual))
+	internal := ProducedByInternal
+	selfMade := "In-house"
+
+	// When
+	material, err1 := CreateMaterial("Lemongrass", "1", MoneyEUR, mtp, 10, MaterialUnitUnits, nil, nil, &internal, nil, nil, nil)
+	_, err2 := CreateMaterial("Lemongrass", "1", MoneyEUR, mtp, 10, MaterialUnitUnits, nil, nil, &selfMade, nil, nil, nil)
+	err3 := material.ChangeProducedBy("self", time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, ProducedByInternal, *material.ProducedBy)
+	assert.Equal(t, &internal, material.UncommittedChanges[0].(MaterialCreated).ProducedBy)
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidProducedBy, Field: "produced_by"}, err2)
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidProducedBy, Field: "produced_by"}, err3)
+	assert.Equal(t, ProducedByInternal, *material.ProducedBy)
+}
+
+func TestTotalInventoryValue(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	m1, _ := CreateMaterial("Bayam Lu Hsieh", "2.5", MoneyEUR, mts, 4, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	m2, _ := CreateMaterial("Tomato Cherry", "3", MoneyEUR, mts, 10, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	m3, _ := CreateMaterial("Kangkung", "15000", MoneyIDR, mts, 5, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	materials := []Material{*m1, *m2, *m3}
+
+	// When
+	eur, err1 := TotalInventoryValue(materials, MoneyEUR)
+	idr, err2 := TotalInventoryValue(materials, MoneyIDR)
+	usd, err3 := TotalInventoryValue(materials, MoneyUSD)
+	_, err4 := TotalInventoryValue(materials, "XYZ")
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, Money{Amount: "40.00", CurrencyCode: MoneyEUR}, eur)
+	assert.Nil(t, err2)
+	assert.Equal(t, Money{Amount: "75000", CurrencyCode: MoneyIDR}, idr)
+	assert.Nil(t, err3)
+	assert.Equal(t, Money{Amount: "0.00", CurrencyCode: MoneyUSD}, usd)
+	assert.Equal(t, errors.New("Wrong currency code"), err4)
+}
+
+func TestReplayVersionZeroMaterialCreated(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	mtp, _ := CreateMaterialTypePlant(PlantTypeHerb)
+
+	seedCreated := MaterialCreated{
+		Name: "Bayam Lu Hsieh",
+		Type: mts,
+	}
+	plantCreated := MaterialCreated{
+		Name: "Mint",
+		Type: mtp,
+	}
+
+	// When
+	seed := NewMaterialFromHistory([]interface{}{seedCreated})
+	plant := NewMaterialFromHistory([]interface{}{plantCreated})
+
+	// Then
+	assert.NotNil(t, seed.IsExpense)
+	assert.True(t, *seed.IsExpense)
+	assert.NotNil(t, plant.IsExpense)
+	assert.False(t, *plant.IsExpense)
+}
+
+func TestCreatePricePerUnitNormalization(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		amount     string
+		expected   string
+		minorUnits int64
+	}{
+		{"10", "10.00", 1000},
+		{"10.0", "10.00", 1000},
+		{"10.00", "10.00", 1000},
+		{"0.1", "0.10", 10},
+		{"2.345", "2.35", 235},
+		{".5", "0.50", 50},
+		{"1e3", "1000.00", 100000},
+	}
+
+	for _, test := range tests {
+		// When
+		ppu, err := CreatePricePerUnit(test.amount, MoneyEUR)
+
+		// Then
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, ppu.Amount)
+
+		minorUnits, err := ppu.MinorUnits()
+		assert.Nil(t, err)
+		assert.Equal(t, test.minorUnits, minorUnits)
+	}
+
+	// When
+	p1, _ := CreatePricePerUnit("10", MoneyEUR)
+	p2, _ := CreatePricePerUnit("10.00", MoneyEUR)
+
+	// Then
+	assert.Equal(t, p1, p2)
+}
+
+func TestPricePerUnitEquals(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		a        PricePerUnit
+		b        PricePerUnit
+		expected bool
+	}{
+		{PricePerUnit{Amount: "10", CurrencyCode: MoneyEUR}, PricePerUnit{Amount: "10.00", CurrencyCode: MoneyEUR}, true},
+		{PricePerUnit{Amount: "10.5", CurrencyCode: MoneyEUR}, PricePerUnit{Amount: "10.50", CurrencyCode: MoneyEUR}, true},
+		{PricePerUnit{Amount: "10", CurrencyCode: MoneyEUR}, PricePerUnit{Amount: "10", CurrencyCode: MoneyUSD}, false},
+		{PricePerUnit{Amount: "10", CurrencyCode: MoneyEUR}, PricePerUnit{Amount: "10.01", CurrencyCode: MoneyEUR}, false},
+		{PricePerUnit{Amount: "abc", CurrencyCode: MoneyEUR}, PricePerUnit{Amount: "abc", CurrencyCode: MoneyEUR}, false},
+	}
+
+	for _, test := range tests {
+		// When
+		result := test.a.Equals(test.b)
+
+		// Then
+		assert.Equal(t, test.expected, result)
+	}
+}
+
+func TestMaterialSkipNoOpChanges(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.ChangeName("  Bayam Lu Hsieh ", time.Now(), uuid.Nil)
+	err2 := material.ChangePricePerUnit("12.00", MoneyEUR, time.Now(), uuid.Nil)
+	err3 := material.ChangeQuantityUnit(20, MaterialUnitPackets, mts, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Nil(t, err3)
+	assert.Len(t, material.UncommittedChanges, 1)
+	assert.Equal(t, 1, material.Version)
+
+	// When
+	material.ChangePricePerUnit("12.50", MoneyEUR, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Len(t, material.UncommittedChanges, 2)
+}
+
+func TestMaterialValidationErrorFields(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	past := time.Now().AddDate(0, 0, -1)
+	producedBy := "NEIGHBOUR"
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	var tests = []struct {
+		fn            func() error
+		expectedField string
+		expectedCode  int
+	}{
+		{func() error {
+			_, err := CreateMaterial("", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+			return err
+		}, "name", MaterialErrorEmptyValue},
+		{func() error {
+			_, err := CreateMaterial("Bayam", "-1", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+			return err
+		}, "price_per_unit", MaterialErrorInvalidPrice},
+		{func() error {
+			_, err := CreateMaterial("Bayam", "12", MoneyEUR, nil, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+			return err
+		}, "type", MaterialErrorEmptyValue},
+		{func() error {
+			_, err := CreateMaterial("Bayam", "12", MoneyEUR, mts, 0, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+			return err
+		}, "quantity", MaterialErrorInvalidQuantity},
+		{func() error {
+			_, err := CreateMaterial("Bayam", "12", MoneyEUR, mts, 20, MaterialUnitBottles, nil, nil, nil, nil, nil, nil)
+			return err
+		}, "quantity_unit", MaterialErrorInvalidQuantityUnit},
+		{func() error {
+			_, err := CreateMaterial("Bayam", "12", MoneyEUR, mts, 20, MaterialUnitPackets, &past, nil, nil, nil, nil, nil)
+			return err
+		}, "expiration_date", MaterialErrorExpirationDateInPast},
+		{func() error {
+			_, err := CreateMaterial("Bayam", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, &producedBy, nil, nil, nil)
+			return err
+		}, "produced_by", MaterialErrorInvalidProducedBy},
+		{func() error {
+			return material.ChangeName(" ", time.Now(), uuid.Nil)
+		}, "name", MaterialErrorEmptyValue},
+	}
+
+	for _, test := range tests {
+		// When
+		err := test.fn()
+
+		// Then
+		var materialErr MaterialError
+		assert.True(t, errors.As(err, &materialErr), "field %s", test.expectedField)
+		assert.Equal(t, test.expectedField, materialErr.Field)
+		assert.Equal(t, test.expectedCode, materialErr.Code, "field %s", test.expectedField)
+	}
+}
+
+func TestMaterialQuantityValidationMessages(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	mta, _ := CreateMaterialTypeAgrochemical(ChemicalTypeFertilizer)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	_, err1 := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 0, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	_, err2 := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitBottles, nil, nil, nil, nil, nil, nil)
+	err3 := material.ChangeQuantityUnit(-1, MaterialUnitPackets, mts, time.Now(), uuid.Nil)
+	err4 := material.ChangeQuantityUnit(10, MaterialUnitSeeds, mta, time.Now(), uuid.Nil)
+
+	// Then
+	assert.EqualError(t, err1, "quantity must be greater than zero")
+	assert.EqualError(t, err2, "invalid quantity unit for material type")
+	assert.EqualError(t, err3, "quantity must be greater than zero")
+	assert.EqualError(t, err4, "invalid quantity unit for material type")
+	assert.EqualError(t, MaterialError{Code: MaterialErrorEmptyValue, Field: "name"}, "cannot be empty")
+}
+
+func TestMaterialChangeHistory(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	operator1, _ := uuid.NewV4()
+	operator2, _ := uuid.NewV4()
+	changedAt1 := time.Date(2026, time.March, 1, 8, 0, 0, 0, time.UTC)
+	changedAt2 := time.Date(2026, time.March, 2, 9, 30, 0, 0, time.UTC)
+	notes := "Moved to the cold room"
+
+	// When
+	material.ChangeName("Bayam Merah", changedAt1, operator1)
+	material.ChangePricePerUnit("13.5", MoneyEUR, changedAt1, operator1)
+	material.MarkChangesCommitted()
+	material.ChangeNotes(&notes, changedAt2, operator2)
+
+	// Then
+	expected := []ChangeRecord{
+		{Field: "name", Value: "Bayam Merah", ChangedAt: changedAt1, ChangedBy: operator1},
+		{Field: "price_per_unit", Value: "13.50 EUR", ChangedAt: changedAt1, ChangedBy: operator1},
+		{Field: "notes", Value: notes, ChangedAt: changedAt2, ChangedBy: operator2},
+	}
+	assert.Equal(t, expected, material.ChangeHistory())
+
+	// When
+	events := []interface{}{
+		MaterialCreated{UID: material.UID, Name: "Bayam Lu Hsieh", Type: mts},
+		MaterialNameChanged{MaterialUID: material.UID, Name: "Bayam Merah", ChangedAt: changedAt1, ChangedBy: operator1},
+	}
+	replayed := NewMaterialFromHistory(events)
+	replayed.ChangeQuantityUnit(5, MaterialUnitPackets, mts, changedAt2, operator2)
+
+	// Then
+	assert.Equal(t, []ChangeRecord{
+		{Field: "name", Value: "Bayam Merah", ChangedAt: changedAt1, ChangedBy: operator1},
+		{Field: "quantity", Value: "5 PACKETS", ChangedAt: changedAt2, ChangedBy: operator2},
+	}, replayed.ChangeHistory())
+}
+
+func TestMaterialReservation(t *testing.T) {
+	// Given
+	mta, _ := CreateMaterialTypeAgrochemical(ChemicalTypeFertilizer)
+	material, _ := CreateMaterial("NPK 16-16-16", "5", MoneyEUR, mta, 10, MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.Reserve(4)
+	err2 := material.Reserve(6)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, float32(10), material.Reserved)
+	assert.Equal(t, float32(0), material.AvailableQuantity())
+	assert.Equal(t, float32(10), material.Quantity.Value)
+
+	// When
+	err3 := material.Reserve(1)
+	err4 := material.Reserve(0)
+
+	// Then
+	assert.Equal(t, errors.New("insufficient available quantity"), err3)
+	assert.Equal(t, errors.New("amount must be greater than zero"), err4)
+	assert.Len(t, material.UncommittedChanges, 3)
+
+	// When
+	material.ReleaseReservation(4)
+
+	// Then
+	assert.Equal(t, float32(6), material.Reserved)
+	assert.Equal(t, float32(4), material.AvailableQuantity())
+
+	// When
+	material.ReleaseReservation(20)
+	material.ReleaseReservation(1)
+
+	// Then
+	assert.Equal(t, float32(0), material.Reserved)
+	assert.Equal(t, float32(10), material.AvailableQuantity())
+	assert.Len(t, material.UncommittedChanges, 5)
+	assert.Equal(t, material.Reserved, NewMaterialFromHistory(material.UncommittedChanges).Reserved)
+}
+
+func TestMaterialSupplier(t *testing.T) {
+	// Given
+	supplier1, _ := uuid.NewV4()
+	supplier2, _ := uuid.NewV4()
+	mta, _ := CreateMaterialTypeAgrochemical(ChemicalTypeFertilizer)
+
+	// When
+	material, err := CreateMaterial("NPK 16-16-16", "5", MoneyEUR, mta, 10, MaterialUnitBags, nil, nil, nil, nil, &supplier1, nil)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, supplier1, *material.SupplierID)
+
+	event, ok := material.UncommittedChanges[0].(MaterialCreated)
+	assert.True(t, ok)
+	assert.Equal(t, supplier1, *event.SupplierID)
+
+	// When
+	err = material.ChangeSupplier(&supplier2, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, supplier2, *material.SupplierID)
+	assert.Equal(t, supplier2, *NewMaterialFromHistory(material.UncommittedChanges).SupplierID)
+
+	// When
+	material.ChangeSupplier(nil, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, material.SupplierID)
+}
+
+func TestArchiveMaterial(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.Archive()
+	err2 := material.Archive()
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, errors.New("material is already archived"), err2)
+	assert.True(t, material.Archived)
+	assert.True(t, NewMaterialFromHistory(material.UncommittedChanges).Archived)
+
+	// When
+	err3 := material.Unarchive()
+	err4 := material.Unarchive()
+
+	// Then
+	assert.Nil(t, err3)
+	assert.Equal(t, errors.New("material is not archived"), err4)
+	assert.False(t, material.Archived)
+	assert.Len(t, material.UncommittedChanges, 3)
+}
+
+func TestCreateMaterialExternalID(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	externalID := " import-2026-0042 "
+	blank := "  "
+
+	// When
+	material1, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, &externalID)
+	material2, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, &blank)
+
+	// Then
+	assert.Equal(t, "import-2026-0042", *material1.ExternalID)
+	assert.Equal(t, "import-2026-0042", *material1.UncommittedChanges[0].(MaterialCreated).ExternalID)
+	assert.Nil(t, material2.ExternalID)
+}
+
+func TestPricePerUnitAdjustByPercent(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	var tests = []struct {
+		price    PricePerUnit
+		percent  float64
+		expected PricePerUnit
+	}{
+		{PricePerUnit{Amount: "10.00", CurrencyCode: MoneyEUR}, 5, PricePerUnit{Amount: "10.50", CurrencyCode: MoneyEUR}},
+		{PricePerUnit{Amount: "3.35", CurrencyCode: MoneyEUR}, 10, PricePerUnit{Amount: "3.69", CurrencyCode: MoneyEUR}},
+		{PricePerUnit{Amount: "45000", CurrencyCode: MoneyIDR}, -20, PricePerUnit{Amount: "36000", CurrencyCode: MoneyIDR}},
+	}
+
+	for _, test := range tests {
+		// When
+		actual, err := test.price.AdjustByPercent(test.percent)
+
+		// Then
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, actual)
+	}
+
+	_, err := PricePerUnit{Amount: "10.00", CurrencyCode: MoneyEUR}.AdjustByPercent(-150)
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidPrice, Field: "price_per_unit"}, err)
+}
+
+func TestMaterialSnapshot(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	notes := "Keep in a cool place"
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, &notes, nil, nil, nil, nil)
+	material.ChangeName("Bayam Hijau", time.Now(), uuid.Nil)
+	material.Reserve(5)
+
+	snap := material.ToSnapshot()
+	snapshotEvents := len(material.UncommittedChanges)
+
+	// When
+	material.ChangePricePerUnit("13.5", MoneyEUR, time.Now(), uuid.Nil)
+	newNotes := "Keep in the fridge"
+	material.ChangeNotes(&newNotes, time.Now(), uuid.Nil)
+	material.ReleaseReservation(2)
+	material.Archive()
+
+	laterEvents := material.UncommittedChanges[snapshotEvents:]
+	fromSnapshot := NewMaterialFromSnapshot(snap, laterEvents)
+
+	// Then
+	assert.Equal(t, snapshotEvents, snap.Version)
+	assert.Empty(t, snap.State.UncommittedChanges)
+	assert.Equal(t, NewMaterialFromHistory(material.UncommittedChanges), fromSnapshot)
+	assert.Equal(t, material.Version, fromSnapshot.Version)
+	assert.Len(t, fromSnapshot.ChangeHistory(), 3)
+	assert.Equal(t, "Bayam Hijau", snap.State.Name)
+}
+
+func TestMaterialTags(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.AddTag("Organic")
+	err2 := material.AddTag(" organic ")
+	err3 := material.AddTag("imported")
+	err4 := material.AddTag("  ")
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Nil(t, err3)
+	assert.Equal(t, MaterialError{Code: MaterialErrorEmptyValue, Field: "tag"}, err4)
+	assert.Equal(t, []string{"organic", "imported"}, material.Tags)
+	assert.True(t, material.HasTag("ORGANIC"))
+	assert.Len(t, material.UncommittedChanges, 3)
+
+	// When
+	err5 := material.RemoveTag("Organic")
+	err6 := material.RemoveTag("bulk")
+
+	// Then
+	assert.Nil(t, err5)
+	assert.Nil(t, err6)
+	assert.Equal(t, []string{"imported"}, material.Tags)
+	assert.False(t, material.HasTag("organic"))
+	assert.Equal(t, material.Tags, NewMaterialFromHistory(material.UncommittedChanges).Tags)
+}
+
+func TestMaterialTotalValue(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "4.00", MoneyEUR, mts, 2.5, MaterialUnitKilogram, nil, nil, nil, nil, nil, nil)
+
+	// When
+	value, err := material.TotalValue()
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, Money{Amount: "10.00", CurrencyCode: MoneyEUR}, value)
+
+	// Given
+	material.PricePerUnit.Amount = "not a number"
+
+	// When
+	_, err = material.TotalValue()
+
+	// Then
+	assert.NotNil(t, err)
+}
+
+func TestMaterialDiff(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	renamed := material.Clone()
+	renamed.ChangeName("Bayam Hijau", time.Now(), uuid.Nil)
+
+	repriced := material.Clone()
+	repriced.ChangePricePerUnit("13.5", MoneyEUR, time.Now(), uuid.Nil)
+
+	// When
+	nameDiff := material.Diff(renamed)
+	priceDiff := material.Diff(repriced)
+	noDiff := material.Diff(material.Clone())
+
+	// Then
+	assert.Equal(t, []FieldChange{{Field: "name", OldValue: "Bayam Lu Hsieh", NewValue: "Bayam Hijau"}}, nameDiff)
+	assert.Equal(t, []FieldChange{{Field: "price_per_unit", OldValue: "12.00 EUR", NewValue: "13.50 EUR"}}, priceDiff)
+	assert.Empty(t, noDiff)
+}
+
+func TestMaterialQuantityInUnit(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 500, MaterialUnitGram, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.RestockQuantityIn(200, MaterialUnitGram, nil)
+	err2 := material.ConsumeQuantityIn(0.5, MaterialUnitKilogram)
+	err3 := material.RestockQuantityIn(5, MaterialUnitPieces, nil)
+	err4 := material.ConsumeQuantityIn(1, MaterialUnitPackets)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidQuantityUnit, Field: "quantity_unit"}, err3)
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidQuantityUnit, Field: "quantity_unit"}, err4)
+	assert.Equal(t, float32(200), material.Quantity.Value)
+	assert.Len(t, material.UncommittedChanges, 3)
+}
+
+func TestMaterialCreatedDate(t *testing.T) {
+	// Given
+	before := time.Now()
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+
+	// When
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.ChangeName("Bayam Hijau", time.Now(), uuid.Nil)
+
+	// Then
+	assert.False(t, material.CreatedDate.IsZero())
+	assert.False(t, material.CreatedDate.Before(before))
+	assert.Equal(t, material.CreatedDate, material.UncommittedChanges[0].(MaterialCreated).CreatedDate)
+	assert.Equal(t, material.CreatedDate, NewMaterialFromHistory(material.UncommittedChanges).CreatedDate)
+}
+
+func TestCreateMoneyRounding(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	var tests = []struct {
+		amount       string
+		currencyCode CurrencyCode
+		expected     Money
+	}{
+		{"1000.50", MoneyIDR, Money{Amount: "1001", CurrencyCode: MoneyIDR}},
+		{"1000.49", MoneyIDR, Money{Amount: "1000", CurrencyCode: MoneyIDR}},
+		{"1000.495", MoneyIDR, Money{Amount: "1000", CurrencyCode: MoneyIDR}},
+		{"1000.50", MoneyEUR, Money{Amount: "1000.50", CurrencyCode: MoneyEUR}},
+		{"3.335", MoneyUSD, Money{Amount: "3.34", CurrencyCode: MoneyUSD}},
+	}
+
+	for _, test := range tests {
+		// When
+		money, err := CreateMoney(test.amount, test.currencyCode)
+
+		// Then
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, money)
+	}
+
+	// When
+	_, err := CreateMoney("-1", MoneyEUR)
+
+	// Then
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidPrice, Field: "price_per_unit"}, err)
+}
+
+func TestMaterialQuantityGranularity(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+
+	// When
+	_, err1 := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 2.5, MaterialUnitSeeds, nil, nil, nil, nil, nil, nil)
+	material, err2 := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 2.5, MaterialUnitKilogram, nil, nil, nil, nil, nil, nil)
+	err3 := material.ChangeQuantityUnit(1.5, MaterialUnitPackets, mts, time.Now(), uuid.Nil)
+	err4 := material.ChangeQuantityUnit(3, MaterialUnitPackets, mts, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Equal(t, MaterialError{Code: MaterialErrorFractionalQuantity, Field: "quantity"}, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, MaterialError{Code: MaterialErrorFractionalQuantity, Field: "quantity"}, err3)
+	assert.Nil(t, err4)
+	assert.Equal(t, float32(3), material.Quantity.Value)
+}
+
+func TestMaterialQuantityGranularityOfStockChanges(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 10, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	fractional := MaterialError{Code: MaterialErrorFractionalQuantity, Field: "quantity"}
+
+	// When
+	err1 := material.ConsumeQuantity(1.5)
+	err2 := material.RestockQuantity(1.5, nil)
+	err3 := material.AddLot("LOT-001", 1.5, nil)
+	err4 := material.Reserve(1.5)
+	err5 := material.ConsumeQuantityIn(1.5, MaterialUnitPackets)
+
+	// Then
+	assert.Equal(t, fractional, err1)
+	assert.Equal(t, fractional, err2)
+	assert.Equal(t, fractional, err3)
+	assert.Equal(t, fractional, err4)
+	assert.Equal(t, fractional, err5)
+	assert.Equal(t, float32(10), material.Quantity.Value)
+	assert.Len(t, material.UncommittedChanges, 1)
+}
+
+func TestCreateProducedMaterial(t *testing.T) {
+	// Given
+	cropUID, _ := uuid.NewV4()
+	plant, _ := CreateMaterialTypePlantWithPackaging(PlantTypeVegetable, string(PlantPackagingIndividual))
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+
+	// When
+	material, err1 := CreateProducedMaterial("Bayam Hijau", "3", MoneyEUR, plant, 40, MaterialUnitUnits, nil, nil, cropUID)
+	_, err2 := CreateProducedMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, cropUID)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.False(t, *material.IsExpense)
+	assert.Equal(t, ProducedByInternal, *material.ProducedBy)
+	assert.Len(t, material.UncommittedChanges, 2)
+
+	event, ok := material.UncommittedChanges[1].(MaterialProduced)
+	assert.True(t, ok)
+	assert.Equal(t, material.UID, event.MaterialUID)
+	assert.Equal(t, cropUID, event.CropUID)
+	assert.Equal(t, material.Quantity, event.Quantity)
+
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}, err2)
+}
+
+func TestSplitMaterial(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 10, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.Reserve(5)
+
+	// When
+	split, err1 := material.Split(3)
+	_, err2 := material.Split(3)
+	_, err3 := material.Split(0.5)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, float32(7), material.Quantity.Value)
+	assert.Equal(t, MaterialQuantityConsumed{MaterialUID: material.UID, Amount: 3}, material.UncommittedChanges[len(material.UncommittedChanges)-1])
+
+	assert.NotEqual(t, material.UID, split.UID)
+	assert.Equal(t, float32(3), split.Quantity.Value)
+	assert.Equal(t, material.Quantity.Unit, split.Quantity.Unit)
+	assert.Equal(t, material.PricePerUnit, split.PricePerUnit)
+	assert.Equal(t, material.Type, split.Type)
+	assert.Len(t, split.UncommittedChanges, 1)
+	assert.Equal(t, split.UID, split.UncommittedChanges[0].(MaterialCreated).UID)
+
+	assert.Equal(t, errors.New("insufficient available quantity"), err2)
+	assert.Equal(t, MaterialError{Code: MaterialErrorFractionalQuantity, Field: "quantity"}, err3)
+}
+
+func TestMergeMaterials(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	mta, _ := CreateMaterialTypeAgrochemical(ChemicalTypeFertilizer)
+	early := time.Now().AddDate(0, 1, 0)
+	late := time.Now().AddDate(0, 6, 0)
+
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 10, MaterialUnitPackets, &late, nil, nil, nil, nil, nil)
+	duplicate, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 4, MaterialUnitPackets, &early, nil, nil, nil, nil, nil)
+	urea, _ := CreateMaterial("Urea", "5", MoneyEUR, mta, 4, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	idr, _ := CreateMaterial("Bayam Lu Hsieh", "15000", MoneyIDR, mts, 4, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.Merge(duplicate)
+	err2 := material.Merge(urea)
+	err3 := material.Merge(idr)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, float32(14), material.Quantity.Value)
+	assert.Equal(t, early, *material.ExpirationDate)
+	assert.True(t, duplicate.Archived)
+	assert.Equal(t, material.Quantity, NewMaterialFromHistory(material.UncommittedChanges).Quantity)
+
+	assert.Equal(t, errors.New("cannot merge materials of different types"), err2)
+	assert.False(t, urea.Archived)
+	assert.Equal(t, errors.New("cannot merge materials of different currencies"), err3)
+	assert.Equal(t, float32(14), material.Quantity.Value)
+}
+
+func TestMergeMaterialsCarriesLotsAndReservations(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	early := time.Now().AddDate(0, 1, 0)
+	late := time.Now().AddDate(0, 6, 0)
+
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 1, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.AddLot("LOT-A", 5, &late)
+	material.Reserve(2)
+
+	duplicate, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 2, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	duplicate.AddLot("LOT-A", 3, &early)
+	duplicate.AddLot("LOT-B", 4, nil)
+	duplicate.Reserve(6)
+
+	archived, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 4, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	archived.Archive()
+	version := material.Version
+
+	// When
+	err1 := material.Merge(archived)
+	err2 := material.Merge(duplicate)
+
+	// Then
+	assert.Equal(t, errors.New("material is already archived"), err1)
+	assert.Equal(t, version+1, material.Version)
+
+	assert.Nil(t, err2)
+	assert.Equal(t, float32(15), material.Quantity.Value)
+	assert.Equal(t, float32(8), material.Reserved)
+	assert.Equal(t, []MaterialLot{
+		{LotNumber: "LOT-A", Quantity: 8, ExpirationDate: &early},
+		{LotNumber: "LOT-B", Quantity: 4},
+	}, material.Lots)
+	assert.True(t, duplicate.Archived)
+
+	replayed := NewMaterialFromHistory(material.UncommittedChanges)
+	assert.Equal(t, material.Lots, replayed.Lots)
+	assert.Equal(t, material.Reserved, replayed.Reserved)
+}
+
+func TestMaterialJSONOmitsNilOptionals(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.IsExpense = nil
+
+	// When
+	data, err := json.Marshal(material)
+
+	// Then
+	assert.Nil(t, err)
+
+	fields := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(data, &fields))
+	for _, key := range []string{"expiration_date", "notes", "produced_by", "is_expense", "supplier_id", "external_id", "low_stock_threshold"} {
+		_, ok := fields[key]
+		assert.False(t, ok, key)
+	}
+	assert.Equal(t, "Bayam Lu Hsieh", fields["name"])
+
+	// Given
+	notes := "Keep in a cool place"
+	material.Notes = &notes
+
+	// When
+	data, _ = json.Marshal(material)
+
+	// Then
+	assert.Contains(t, string(data), `"notes":"Keep in a cool place"`)
+}
+
+func TestMaterialQuantityIsZeroIsPositive(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	var tests = []struct {
+		value              float32
+		expectedIsZero     bool
+		expectedIsPositive bool
+	}{
+		{0, true, false},
+		{0.00000001, true, false},
+		{-0.00000001, true, false},
+		{0.5, false, true},
+		{-0.5, false, false},
+	}
+
+	for _, test := range tests {
+		// When
+		q := MaterialQuantity{Value: test.value, Unit: MaterialQuantityUnit{Code: MaterialUnitGram}}
+
+		// Then
+		assert.Equal(t, test.expectedIsZero, q.IsZero(), "value %v", test.value)
+		assert.Equal(t, test.expectedIsPositive, q.IsPositive(), "value %v", test.value)
+	}
+}
+
+func TestConsumeQuantityDepletes(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 0.3, MaterialUnitKilogram, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.ConsumeQuantity(0.1)
+	err2 := material.ConsumeQuantity(0.2)
+	err3 := material.ConsumeQuantity(0.1)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.True(t, material.Quantity.IsZero())
+	assert.Equal(t, float32(0), material.Quantity.Value)
+	assert.Equal(t, errors.New("insufficient quantity"), err3)
+}
+
+func TestParseCurrencyCode(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	var tests = []struct {
+		code          string
+		expected      CurrencyCode
+		expectedError error
+	}{
+		{"eur", CurrencyEUR, nil},
+		{"EUR", CurrencyEUR, nil},
+		{" idr ", CurrencyIDR, nil},
+		{"XYZ", "", errors.New("Wrong currency code")},
+	}
+
+	for _, test := range tests {
+		// When
+		cc, err := ParseCurrencyCode(test.code)
+
+		// Then
+		assert.Equal(t, test.expected, cc, "code %q", test.code)
+		assert.Equal(t, test.expectedError, err, "code %q", test.code)
+	}
+
+	// When
+	money, err1 := CreateMoney("12", "eur")
+	_, err2 := CreateMoneyFromString("12", "XYZ")
+	price, err3 := CreatePricePerUnit("12", "eur")
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, Money{Amount: "12.00", CurrencyCode: MoneyEUR}, money)
+	assert.Equal(t, errors.New("Wrong currency code"), err2)
+	assert.Nil(t, err3)
+	assert.Equal(t, MoneyEUR, price.CurrencyCode)
+}
+
+func TestMaterialPriceHistory(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	created := material.CreatedDate
+	firstChange := created.AddDate(0, 1, 0)
+	secondChange := created.AddDate(0, 2, 0)
+
+	// When
+	material.ChangePricePerUnit("13", MoneyEUR, firstChange, uuid.Nil)
+	material.ChangePricePerUnit("15", MoneyEUR, secondChange, uuid.Nil)
+
+	// Then
+	history := material.PriceHistory()
+	assert.Equal(t, []PricedAt{
+		{Price: PricePerUnit{Amount: "12.00", CurrencyCode: MoneyEUR}, EffectiveFrom: created},
+		{Price: PricePerUnit{Amount: "13.00", CurrencyCode: MoneyEUR}, EffectiveFrom: firstChange},
+		{Price: PricePerUnit{Amount: "15.00", CurrencyCode: MoneyEUR}, EffectiveFrom: secondChange},
+	}, history)
+	assert.Equal(t, history, NewMaterialFromHistory(material.UncommittedChanges).PriceHistory())
+
+	between, found1 := material.PriceAt(created.AddDate(0, 1, 15))
+	_, found2 := material.PriceAt(created.AddDate(0, 0, -1))
+	latest, _ := material.PriceAt(secondChange)
+
+	assert.True(t, found1)
+	assert.Equal(t, "13.00", between.Amount)
+	assert.False(t, found2)
+	assert.Equal(t, "15.00", latest.Amount)
+}
+
+func TestCreateMaterials(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	specs := []MaterialSpec{
+		{Name: "Bayam Lu Hsieh", Price: "12", PriceUnit: MoneyEUR, Type: mts, Quantity: 20, QuantityUnit: MaterialUnitPackets},
+		{Name: "Kangkung", Price: "8", PriceUnit: MoneyEUR, Type: mts, Quantity: 0, QuantityUnit: MaterialUnitPackets},
+		{Name: "Tomato Cherry", Price: "3", PriceUnit: MoneyEUR, Type: mts, Quantity: 10, QuantityUnit: MaterialUnitPackets},
+	}
+
+	// When
+	materials, err := CreateMaterials(specs)
+
+	// Then
+	assert.Nil(t, materials)
+	assert.Equal(t, MaterialSpecErrors{
+		{Index: 1, Err: MaterialError{Code: MaterialErrorInvalidQuantity, Field: "quantity"}},
+	}, err)
+	assert.Equal(t, "material 1: quantity must be greater than zero", err.Error())
+
+	// Given
+	specs[1].Quantity = 5
+
+	// When
+	materials, err = CreateMaterials(specs)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Len(t, materials, 3)
+	assert.Equal(t, "Kangkung", materials[1].Name)
+}
+
+func TestMaterialValidate(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	valid, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	invalid := valid.Clone()
+	invalid.Quantity.Unit = MaterialQuantityUnit{Code: MaterialUnitBottles, Label: "Bottles"}
+
+	depleted := valid.Clone()
+	depleted.ConsumeQuantity(20)
+
+	// When
+	err1 := valid.Validate()
+	err2 := invalid.Validate()
+	err3 := depleted.Validate()
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidQuantityUnit, Field: "quantity_unit"}, err2)
+	assert.Nil(t, err3)
+}
+
+func TestMaterialComponents(t *testing.T) {
+	// Given
+	mtgm := MaterialTypeGrowingMedium{}
+	compost, _ := CreateMaterial("Compost", "4", MoneyEUR, mtgm, 10, MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+	perlite, _ := CreateMaterial("Perlite", "7.50", MoneyEUR, mtgm, 10, MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+	mix, _ := CreateMaterial("Seedling Mix", "0", MoneyEUR, mtgm, 1, MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := mix.AddComponent(compost.UID, 2)
+	err2 := mix.AddComponent(perlite.UID, 0.5)
+	err3 := mix.AddComponent(mix.UID, 1)
+
+	cost, err4 := mix.ComputeCost(map[uuid.UUID]Material{
+		compost.UID: *compost,
+		perlite.UID: *perlite,
+	})
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, errors.New("material cannot contain itself"), err3)
+	assert.Len(t, mix.Components, 2)
+	assert.Nil(t, err4)
+	assert.Equal(t, Money{Amount: "11.75", CurrencyCode: MoneyEUR}, cost)
+
+	// When
+	err5 := mix.RemoveComponent(compost.UID)
+	err6 := mix.RemoveComponent(compost.UID)
+
+	// Then
+	assert.Nil(t, err5)
+	assert.Equal(t, errors.New("component not found"), err6)
+	assert.Equal(t, []MaterialComponent{{MaterialUID: perlite.UID, Quantity: 0.5}}, mix.Components)
+	assert.Equal(t, mix.Components, NewMaterialFromHistory(mix.UncommittedChanges).Components)
+}
+
+func TestCreateMaterialWithFixedUID(t *testing.T) {
+	// Given
+	fixed := uuid.FromStringOrNil("5f0dc2a4-7c6e-4b5a-9d1c-2a4f6e8b9c01")
+
+	defaultGenerator := NewMaterialUID
+	NewMaterialUID = func() (uuid.UUID, error) { return fixed, nil }
+	defer func() { NewMaterialUID = defaultGenerator }()
+
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+
+	// When
+	material, err := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, fixed, material.UID)
+	assert.Equal(t, fixed, material.UncommittedChanges[0].(MaterialCreated).UID)
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestMaterialClock(t *testing.T) {
+	// Given
+	now := time.Date(2099, time.March, 1, 8, 0, 0, 0, time.UTC)
+
+	defaultClock := MaterialClock
+	MaterialClock = fixedClock{now: now}
+	defer func() { MaterialClock = defaultClock }()
+
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	material.Archive()
+	// In the future for the system clock but in the past for the fixed one
+	expDate := now.AddDate(0, 0, -1)
+	err := material.ChangeExpirationDate(&expDate, now, uuid.Nil)
+
+	// Then
+	assert.Equal(t, now, material.CreatedDate)
+	assert.Equal(t, now, material.UncommittedChanges[1].(MaterialArchived).ArchivedDate)
+	assert.Equal(t, MaterialError{Code: MaterialErrorExpirationDateInPast, Field: "expiration_date"}, err)
+}
+
+func TestMaterialAttachments(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err1 := material.AddAttachment("materials/bayam-label.jpg", "Image/JPEG")
+	err2 := material.AddAttachment("materials/bayam-invoice.pdf", "application/pdf")
+	err3 := material.AddAttachment(" ", "image/png")
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, errors.New("attachment must be an image"), err2)
+	assert.Equal(t, MaterialError{Code: MaterialErrorEmptyValue, Field: "attachment"}, err3)
+	assert.Equal(t, []Attachment{{Key: "materials/bayam-label.jpg", ContentType: "image/jpeg"}}, material.Attachments)
+
+	// When
+	err4 := material.RemoveAttachment("materials/bayam-label.jpg")
+	err5 := material.RemoveAttachment("materials/bayam-label.jpg")
+
+	// Then
+	assert.Nil(t, err4)
+	assert.Equal(t, errors.New("attachment not found"), err5)
+	assert.Empty(t, material.Attachments)
+	assert.Empty(t, NewMaterialFromHistory(material.UncommittedChanges).Attachments)
+}
+
+func TestPlantPackagingQuantityUnit(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		packaging    string
+		quantityUnit string
+		expected     error
+	}{
+		{"", MaterialUnitPackets, MaterialError{Code: MaterialErrorEmptyValue, Field: "packaging"}},
+		{"", MaterialUnitUnits, MaterialError{Code: MaterialErrorEmptyValue, Field: "packaging"}},
+		{string(PlantPackagingSeedPackets), MaterialUnitPackets, nil},
+		{string(PlantPackagingIndividual), MaterialUnitUnits, nil},
+		{string(PlantPackagingSeedPackets), MaterialUnitUnits, MaterialError{Code: MaterialErrorInvalidQuantityUnit, Field: "quantity_unit"}},
+		{string(PlantPackagingIndividual), MaterialUnitPackets, MaterialError{Code: MaterialErrorInvalidQuantityUnit, Field: "quantity_unit"}},
+	}
+
+	for _, test := range tests {
+		// Given
+		mtp, err := CreateMaterialTypePlantWithPackaging(PlantTypeVegetable, test.packaging)
+		assert.Nil(t, err)
+
+		// When
+		_, err = CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mtp, 20, test.quantityUnit, nil, nil, nil, nil, nil, nil)
+
+		// Then
+		assert.Equal(t, test.expected, err, test.packaging+" "+test.quantityUnit)
+	}
+}
+
+func TestCreateMaterialTypePlantWithUnknownPackaging(t *testing.T) {
+	// When
+	_, err := CreateMaterialTypePlantWithPackaging(PlantTypeVegetable, "BOXES")
+
+	// Then
+	assert.Equal(t, InventoryMaterialError{InventoryMaterialErrorWrongType}, err)
+}
+
+func TestChangePlantTypeKeepsPackaging(t *testing.T) {
+	// Given
+	mtp, _ := CreateMaterialTypePlantWithPackaging(PlantTypeVegetable, string(PlantPackagingIndividual))
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mtp, 20, MaterialUnitUnits, nil, nil, nil, nil, nil, nil)
+
+	// When
+	err := material.ChangePlantType(PlantTypeHerb, time.Now(), uuid.Nil)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, MaterialTypePlant{PlantType: GetPlantType(PlantTypeHerb), Packaging: PlantPackagingIndividual}, material.Type)
+}
+
+func TestMaterialQuantityNeverNegative(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	material.Reserve(5)
+	material.ReleaseReservation(10)
+	material.ConsumeQuantity(15)
+	material.ConsumeQuantity(15)
+	material.ConsumeQuantityIn(1, MaterialUnitPackets)
+	material.Split(3)
+	material.ConsumeFromLot(2)
+
+	// Then
+	assert.True(t, material.Quantity.Value >= 0)
+	assert.True(t, material.Reserved >= 0)
+
+	// When
+	replayed := NewMaterialFromHistory([]interface{}{
+		material.UncommittedChanges[0],
+		MaterialQuantityReserved{MaterialUID: material.UID, Amount: 5},
+		MaterialQuantityConsumed{MaterialUID: material.UID, Amount: 30},
+		MaterialReservationReleased{MaterialUID: material.UID, Amount: 10},
+	})
+
+	// Then
+	assert.Equal(t, float32(0), replayed.Quantity.Value)
+	assert.Equal(t, float32(0), replayed.Reserved)
+}
+
+func TestUnmarshalMaterialQuantity(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+
+	// When
+	q, err1 := UnmarshalMaterialQuantity([]byte(`{"value": 20, "unit": {"code": "PACKETS"}}`), mts)
+	_, err2 := UnmarshalMaterialQuantity([]byte(`{"value": 20, "unit": {"code": "BOTTLES", "label": "Bottles"}}`), mts)
+	_, err3 := UnmarshalMaterialQuantity([]byte(`{"value": "twenty"}`), mts)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, MaterialQuantity{Value: 20, Unit: MaterialQuantityUnit{Code: MaterialUnitPackets, Label: "Packets"}}, q)
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidQuantityUnit, Field: "quantity_unit"}, err2)
+	assert.NotNil(t, err3)
+}
+
+func TestMaterialCorrectQuantity(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitKilogram, nil, nil, nil, nil, nil, nil)
+	unit := material.Quantity.Unit
+
+	// When
+	err1 := material.CorrectQuantity(18.5)
+	err2 := material.CorrectQuantity(-1)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, MaterialErrorInvalidQuantity, err2.(MaterialError).Code)
+	assert.Equal(t, MaterialQuantity{Value: 18.5, Unit: unit}, material.Quantity)
+	assert.Len(t, material.UncommittedChanges, 2)
+
+	event, ok := material.UncommittedChanges[1].(MaterialQuantityChanged)
+	assert.True(t, ok)
+	assert.Equal(t, unit, event.Quantity.Unit)
+}
+
+func TestMaterialQuantityRounding(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 1, MaterialUnitKilogram, nil, nil, nil, nil, nil, nil)
+
+	// When
+	for i := 0; i < 7; i++ {
+		material.ConsumeQuantity(0.1)
+	}
+
+	// Then
+	assert.Equal(t, float32(0.3), material.Quantity.Value)
+	assert.Equal(t, float32(0.3), NewMaterialFromHistory(material.UncommittedChanges).Quantity.Value)
+
+	// When
+	material.RestockQuantity(0.1, nil)
+	material.RestockQuantity(0.2, nil)
+
+	// Then
+	assert.Equal(t, float32(0.6), material.Quantity.Value)
+}
+
+func TestMaterialSupplierSKUs(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 20, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	supplierA, _ := uuid.NewV4()
+	supplierB, _ := uuid.NewV4()
+
+	// When
+	err1 := material.SetSupplierSKU(supplierA, " TS-0042 ")
+	err2 := material.SetSupplierSKU(supplierB, "BIBIT-7")
+	err3 := material.SetSupplierSKU(supplierA, "TS-0043")
+	err4 := material.SetSupplierSKU(supplierA, "TS-0043")
+	err5 := material.SetSupplierSKU(supplierA, " ")
+	err6 := material.SetSupplierSKU(uuid.Nil, "TS-0043")
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Nil(t, err3)
+	assert.Nil(t, err4)
+	assert.Equal(t, MaterialError{Code: MaterialErrorEmptyValue, Field: "sku"}, err5)
+	assert.Equal(t, MaterialError{Code: MaterialErrorEmptyValue, Field: "supplier_id"}, err6)
+	assert.Equal(t, map[uuid.UUID]string{supplierA: "TS-0043", supplierB: "BIBIT-7"}, material.SupplierSKUs)
+	assert.Len(t, material.UncommittedChanges, 4)
+
+	// When
+	err7 := material.RemoveSupplierSKU(supplierB)
+	err8 := material.RemoveSupplierSKU(supplierB)
+
+	// Then
+	assert.Nil(t, err7)
+	assert.Nil(t, err8)
+	assert.Equal(t, map[uuid.UUID]string{supplierA: "TS-0043"}, material.SupplierSKUs)
+	assert.Equal(t, material.SupplierSKUs, NewMaterialFromHistory(material.UncommittedChanges).SupplierSKUs)
+}
+
+func TestConsumeQuantityUsesEarliestExpiringLotFirst(t *testing.T) {
+	// Given
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	material, _ := CreateMaterial("Bayam Lu Hsieh", "12", MoneyEUR, mts, 2, MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	later := time.Now().AddDate(0, 6, 0)
+	sooner := time.Now().AddDate(0, 1, 0)
+	material.AddLot("LOT-LATER", 5, &later)
+	material.AddLot("LOT-SOONER", 4, &sooner)
+
+	// When
+	err1 := material.ConsumeQuantity(6)
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, float32(5), material.Quantity.Value)
+	assert.Equal(t, []MaterialLot{{LotNumber: "LOT-LATER", Quantity: 3, ExpirationDate: &later}}, material.Lots)
+
+	// When
+	err2 := material.ConsumeQuantity(4)
+
+	// Then
+	assert.Nil(t, err2)
+	assert.Equal(t, float32(1), material.Quantity.Value)
+	assert.Empty(t, material.Lots)
+	assert.Equal(t, MaterialQuantityConsumed{MaterialUID: material.UID, Amount: 1}, material.UncommittedChanges[len(material.UncommittedChanges)-1])
+}
+
+func TestMoneyFormatted(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		money    Money
+		locale   string
+		expected string
+	}{
+		{Money{Amount: "1000000", CurrencyCode: MoneyIDR}, "id-ID", "Rp1.000.000"},
+		{Money{Amount: "1000000", CurrencyCode: MoneyIDR}, "en", "Rp1,000,000"},
+		{Money{Amount: "999.60", CurrencyCode: MoneyIDR}, "id", "Rp1.000"},
+		{Money{Amount: "1000", CurrencyCode: MoneyEUR}, "en", "€1,000.00"},
+		{Money{Amount: "1234567.5", CurrencyCode: MoneyEUR}, "id_ID", "€1.234.567,50"},
+		{Money{Amount: "12.5", CurrencyCode: MoneyEUR}, "fr", "€12.50"},
+		{Money{Amount: "12", CurrencyCode: "CHF"}, "en", "CHF 12.00"},
+	}
+
+	for _, test := range tests {
+		// When
+		formatted := test.money.Formatted(test.locale)
+
+		// Then
+		assert.Equal(t, test.expected, formatted)
+	}
+}
@@ -15,6 +15,91 @@ type MaterialType interface {
 	Code() string
 }
 
+// MaterialTypeInfo describes a material type. Perishable types go bad over time,
+// so their materials are expected to have an expiration date.
+type MaterialTypeInfo struct {
+	Code       string `json:"code"`
+	Label      string `json:"label"`
+	Perishable bool   `json:"perishable"`
+}
+
+func ListMaterialTypes() []MaterialTypeInfo {
+	return []MaterialTypeInfo{
+		{Code: MaterialTypeSeedCode, Label: "Seed", Perishable: true},
+		{Code: MaterialTypePlantCode, Label: "Plant", Perishable: true},
+		{Code: MaterialTypeGrowingMediumCode, Label: "Growing Medium", Perishable: true},
+		{Code: MaterialTypeAgrochemicalCode, Label: "Agrochemical", Perishable: true},
+		{Code: MaterialTypeLabelAndCropSupportCode, Label: "Label and Crop Support"},
+		{Code: MaterialTypeSeedingContainerCode, Label: "Seeding Container"},
+		{Code: MaterialTypePostHarvestSupplyCode, Label: "Post Harvest Supply", Perishable: true},
+		{Code: MaterialTypeOtherCode, Label: "Other"},
+	}
+}
+
+// IsPerishableType tells whether the material type of the code is perishable.
+// Unknown codes are not.
+func IsPerishableType(code string) bool {
+	for _, v := range ListMaterialTypes() {
+		if v.Code == code {
+			return v.Perishable
+		}
+	}
+
+	return false
+}
+
+// GetMaterialTypeByCode maps a material type code to its concrete MaterialType.
+// Types that carry extra detail (plant, chemical or container type) are returned
+// with that detail empty; use their Create functions to fill it in.
+func GetMaterialTypeByCode(code string) (MaterialType, error) {
+	switch code {
+	case MaterialTypeSeedCode:
+		return MaterialTypeSeed{}, nil
+	case MaterialTypePlantCode:
+		return MaterialTypePlant{}, nil
+	case MaterialTypeGrowingMediumCode:
+		return MaterialTypeGrowingMedium{}, nil
+	case MaterialTypeAgrochemicalCode:
+		return MaterialTypeAgrochemical{}, nil
+	case MaterialTypeLabelAndCropSupportCode:
+		return MaterialTypeLabelAndCropSupport{}, nil
+	case MaterialTypeSeedingContainerCode:
+		return MaterialTypeSeedingContainer{}, nil
+	case MaterialTypePostHarvestSupplyCode:
+		return MaterialTypePostHarvestSupply{}, nil
+	case MaterialTypeOtherCode:
+		return MaterialTypeOther{}, nil
+	}
+
+	return nil, MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}
+}
+
+// CreateMaterialTypeByCode is like GetMaterialTypeByCode, but also fills in the type detail
+// (plant type for seeds and plants, chemical type for agrochemicals, container type for seeding containers).
+func CreateMaterialTypeByCode(code, detail string) (MaterialType, error) {
+	var mt MaterialType
+	var err error
+
+	switch code {
+	case MaterialTypeSeedCode:
+		mt, err = CreateMaterialTypeSeed(detail)
+	case MaterialTypePlantCode:
+		mt, err = CreateMaterialTypePlant(detail)
+	case MaterialTypeAgrochemicalCode:
+		mt, err = CreateMaterialTypeAgrochemical(detail)
+	case MaterialTypeSeedingContainerCode:
+		mt, err = CreateMaterialTypeSeedingContainer(detail)
+	default:
+		return GetMaterialTypeByCode(code)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mt, nil
+}
+
 type MaterialTypeSeed struct {
 	PlantType PlantType
 }
@@ -189,6 +274,7 @@ func (mt MaterialTypeOther) Code() string {
 
 type MaterialTypePlant struct {
 	PlantType PlantType
+	Packaging PlantPackaging
 }
 
 func (mt MaterialTypePlant) Code() string {
@@ -201,5 +287,47 @@ func CreateMaterialTypePlant(plantType string) (MaterialTypePlant, error) {
 		return MaterialTypePlant{}, InventoryMaterialError{InventoryMaterialErrorWrongType}
 	}
 
-	return MaterialTypePlant{pt}, nil
+	return MaterialTypePlant{PlantType: pt}, nil
+}
+
+// CreateMaterialTypePlantWithPackaging is CreateMaterialTypePlant with the plant's packaging set.
+// An empty packaging leaves it unspecified, as on plants stored before packagings existed,
+// but materials can't be created or changed with an unspecified packaging.
+func CreateMaterialTypePlantWithPackaging(plantType, packaging string) (MaterialTypePlant, error) {
+	mt, err := CreateMaterialTypePlant(plantType)
+	if err != nil {
+		return MaterialTypePlant{}, err
+	}
+
+	pp := PlantPackaging(packaging)
+	if pp != PlantPackagingUnspecified && pp.QuantityUnit() == "" {
+		return MaterialTypePlant{}, InventoryMaterialError{InventoryMaterialErrorWrongType}
+	}
+
+	mt.Packaging = pp
+
+	return mt, nil
+}
+
+// PlantPackaging tells how a plant material is counted. Plants bought as seeds in packets
+// are counted in PACKETS, plants counted one by one are counted in UNITS.
+type PlantPackaging string
+
+const (
+	PlantPackagingUnspecified PlantPackaging = ""
+	PlantPackagingSeedPackets PlantPackaging = "SEED_PACKETS"
+	PlantPackagingIndividual  PlantPackaging = "INDIVIDUAL"
+)
+
+// QuantityUnit returns the only quantity unit allowed for the packaging,
+// or an empty string when it is unspecified or unknown.
+func (pp PlantPackaging) QuantityUnit() string {
+	switch pp {
+	case PlantPackagingSeedPackets:
+		return MaterialUnitPackets
+	case PlantPackagingIndividual:
+		return MaterialUnitUnits
+	}
+
+	return ""
 }
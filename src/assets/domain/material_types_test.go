@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMaterialTypeByCode(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		code     string
+		expected MaterialType
+	}{
+		{MaterialTypeSeedCode, MaterialTypeSeed{}},
+		{MaterialTypePlantCode, MaterialTypePlant{}},
+		{MaterialTypeGrowingMediumCode, MaterialTypeGrowingMedium{}},
+		{MaterialTypeAgrochemicalCode, MaterialTypeAgrochemical{}},
+		{MaterialTypeLabelAndCropSupportCode, MaterialTypeLabelAndCropSupport{}},
+		{MaterialTypeSeedingContainerCode, MaterialTypeSeedingContainer{}},
+		{MaterialTypePostHarvestSupplyCode, MaterialTypePostHarvestSupply{}},
+		{MaterialTypeOtherCode, MaterialTypeOther{}},
+	}
+
+	for _, test := range tests {
+		// When
+		mt, err := GetMaterialTypeByCode(test.code)
+
+		// Then
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, mt)
+		assert.Equal(t, test.code, mt.Code())
+	}
+
+	// When
+	mt, err := GetMaterialTypeByCode("WRONG_TYPE")
+
+	// Then
+	assert.Nil(t, mt)
+	assert.Equal(t, MaterialError{Code: MaterialErrorInvalidMaterialType, Field: "type"}, err)
+}
+
+func TestListMaterialTypes(t *testing.T) {
+	// When
+	types := ListMaterialTypes()
+
+	// Then
+	assert.Len(t, types, 8)
+	for _, v := range types {
+		assert.NotEmpty(t, v.Label)
+		assert.NotNil(t, MaterialQuantityUnits(v.Code), v.Code)
+
+		_, err := GetMaterialTypeByCode(v.Code)
+		assert.Nil(t, err)
+	}
+}
+
+func TestIsPerishable(t *testing.T) {
+	t.Parallel()
+
+	mts, _ := CreateMaterialTypeSeed(PlantTypeVegetable)
+	mta, _ := CreateMaterialTypeAgrochemical(ChemicalTypeFertilizer)
+	mtc, _ := CreateMaterialTypeSeedingContainer(ContainerTypeTray)
+
+	var tests = []struct {
+		materialType MaterialType
+		quantityUnit string
+		expected     bool
+	}{
+		{mts, MaterialUnitPackets, true},
+		{mta, MaterialUnitBottles, true},
+		{MaterialTypeGrowingMedium{}, MaterialUnitBags, true},
+		{MaterialTypePostHarvestSupply{}, MaterialUnitPieces, true},
+		{MaterialTypeLabelAndCropSupport{}, MaterialUnitPieces, false},
+		{mtc, MaterialUnitPieces, false},
+		{MaterialTypeOther{}, MaterialUnitPieces, false},
+	}
+
+	for _, test := range tests {
+		// Given
+		material, err := CreateMaterial("Material", "12", MoneyEUR, test.materialType, 20, test.quantityUnit, nil, nil, nil, nil, nil, nil)
+		assert.Nil(t, err)
+
+		// When
+		perishable := material.IsPerishable()
+
+		// Then
+		assert.Equal(t, test.expected, perishable, test.materialType.Code())
+	}
+
+	assert.False(t, IsPerishableType("BOGUS"))
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EventPublisher streams committed events somewhere else, e.g. an external message bus.
+type EventPublisher interface {
+	Publish(event interface{})
+}
+
+// NoopEventPublisher drops every event. It is used when no publisher is set.
+type NoopEventPublisher struct{}
+
+func (p NoopEventPublisher) Publish(event interface{}) {}
+
+// RecordingEventPublisher keeps the published events in memory, e.g. for tests.
+type RecordingEventPublisher struct {
+	lock   sync.Mutex
+	events []interface{}
+}
+
+func (p *RecordingEventPublisher) Publish(event interface{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.events = append(p.events, event)
+}
+
+// Events returns the published events in the order they were published.
+func (p *RecordingEventPublisher) Events() []interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]interface{}(nil), p.events...)
+}
+
+// SubscriberRegistry hands every published event to its subscribers, e.g. to refresh
+// a cache or call a webhook. Set it as the EventPublisher of MaterialService so the
+// handlers only see events that were saved. Its zero value is ready to use.
+type SubscriberRegistry struct {
+	lock        sync.RWMutex
+	nextID      int
+	subscribers []subscriber
+}
+
+type subscriber struct {
+	id      int
+	handler func(event interface{})
+}
+
+// Subscribe adds handler to the registry and returns the function that removes it.
+func (r *SubscriberRegistry) Subscribe(handler func(event interface{})) func() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.nextID++
+	id := r.nextID
+	r.subscribers = append(r.subscribers, subscriber{id: id, handler: handler})
+
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		for i, v := range r.subscribers {
+			if v.id == id {
+				r.subscribers = append(r.subscribers[:i:i], r.subscribers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish calls the handlers in the order they subscribed.
+// A handler that panics is logged and doesn't keep the event from the others.
+func (r *SubscriberRegistry) Publish(event interface{}) {
+	r.lock.RLock()
+	subscribers := append([]subscriber(nil), r.subscribers...)
+	r.lock.RUnlock()
+
+	for _, v := range subscribers {
+		callSubscriber(v.handler, event)
+	}
+}
+
+func callSubscriber(handler func(event interface{}), event interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("material event subscriber panicked: ", r)
+		}
+	}()
+
+	handler(event)
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/query"
+	"github.com/Tanibox/tania-core/src/assets/repository"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	uuid "github.com/satori/go.uuid"
+)
+
+// MaterialService publishes the events of the materials it saves to EventPublisher,
+// or nowhere when it is nil.
+type MaterialService struct {
+	MaterialRepo       repository.MaterialRepository
+	MaterialEventQuery query.MaterialEventQuery
+	EventPublisher     EventPublisher
+}
+
+// SaveMaterial saves the uncommitted changes of the material and then publishes them.
+// Nothing is published when saving fails.
+func (s MaterialService) SaveMaterial(material *domain.Material) error {
+	events := append([]interface{}(nil), material.UncommittedChanges...)
+
+	err := <-s.MaterialRepo.Save(material, material.BaseVersion())
+	if err != nil {
+		return err
+	}
+
+	s.publish(events)
+
+	return nil
+}
+
+// FindOrSaveMaterial saves a new material through MaterialRepo.FindOrSave and publishes its events.
+// When a material with the same ExternalID was already saved, that one is returned
+// and nothing is published.
+func (s MaterialService) FindOrSaveMaterial(material *domain.Material) (*domain.Material, error) {
+	events := append([]interface{}(nil), material.UncommittedChanges...)
+
+	result := <-s.MaterialRepo.FindOrSave(material)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	saved, ok := result.Result.(*domain.Material)
+	if !ok {
+		return nil, errors.New("Internal server error. Error type assertion")
+	}
+
+	if saved.UID == material.UID {
+		s.publish(events)
+	}
+
+	return saved, nil
+}
+
+func (s MaterialService) publish(events []interface{}) {
+	publisher := s.EventPublisher
+	if publisher == nil {
+		publisher = NoopEventPublisher{}
+	}
+
+	for _, v := range events {
+		publisher.Publish(v)
+	}
+}
+
+// AdjustPricesByPercent changes the price of every material of the given type by percent,
+// e.g. 5 for a 5% raise. Each material keeps its currency. The changes are recorded
+// as made by the system (uuid.Nil) and are left uncommitted for the caller to save.
+func (s MaterialService) AdjustPricesByPercent(typeCode string, percent float64) ([]*domain.Material, error) {
+	result := <-s.MaterialRepo.FindAll()
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	materials, ok := result.Result.([]domain.Material)
+	if !ok {
+		return nil, errors.New("Internal server error")
+	}
+
+	changedAt := domain.MaterialClock.Now()
+
+	adjusted := []*domain.Material{}
+	for i := range materials {
+		m := &materials[i]
+
+		if m.Type == nil || m.Type.Code() != typeCode {
+			continue
+		}
+
+		price, err := m.PricePerUnit.AdjustByPercent(percent)
+		if err != nil {
+			return nil, err
+		}
+
+		err = m.ChangePricePerUnit(price.Amount, price.CurrencyCode, changedAt, uuid.Nil)
+		if err != nil {
+			return nil, err
+		}
+
+		adjusted = append(adjusted, m)
+	}
+
+	return adjusted, nil
+}
+
+// ArchiveExpired archives every perishable material whose expiration date is before now
+// and saves it through SaveMaterial. Archived and non-perishable materials are left alone.
+// It returns how many materials were archived, including the ones saved before an error.
+func (s MaterialService) ArchiveExpired(now time.Time) (int, error) {
+	result := <-s.MaterialRepo.FindAll()
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	materials, ok := result.Result.([]domain.Material)
+	if !ok {
+		return 0, errors.New("Internal server error")
+	}
+
+	archived := 0
+	for i := range materials {
+		m := &materials[i]
+
+		if m.Archived || !m.IsPerishable() || m.ExpirationDate == nil || !m.ExpirationDate.Before(now) {
+			continue
+		}
+
+		err := m.Archive()
+		if err != nil {
+			return archived, err
+		}
+
+		err = s.SaveMaterial(m)
+		if err != nil {
+			return archived, err
+		}
+
+		archived++
+	}
+
+	return archived, nil
+}
+
+// SuggestReorder suggests how much of the material to order now so the stock lasts
+// leadTimeDays, the time the order takes to arrive. The average daily usage is taken
+// from the consumptions between the creation of the material and its latest consumption.
+// It is zero when the available quantity already covers the lead time.
+func (s MaterialService) SuggestReorder(uid uuid.UUID, leadTimeDays int) (float32, error) {
+	if leadTimeDays <= 0 {
+		return 0, errors.New("lead time must be greater than zero")
+	}
+
+	result := <-s.MaterialEventQuery.FindAllByID(uid)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	events, ok := result.Result.([]storage.MaterialEvent)
+	if !ok {
+		return 0, errors.New("Internal server error")
+	}
+
+	if len(events) == 0 {
+		return 0, repository.ErrMaterialNotFound
+	}
+
+	consumed := 0.0
+	lastConsumed := time.Time{}
+	for _, v := range events {
+		if amount, ok := consumedAmount(v.Event); ok {
+			consumed += float64(amount)
+			lastConsumed = v.CreatedDate
+		}
+	}
+
+	days := lastConsumed.Sub(events[0].CreatedDate).Hours() / 24
+	if consumed == 0 || days < 1 {
+		return 0, errors.New("insufficient consumption history")
+	}
+
+	material := repository.NewMaterialFromHistory(events)
+
+	suggested := consumed/days*float64(leadTimeDays) - float64(material.AvailableQuantity())
+	if suggested < 0 {
+		return 0, nil
+	}
+
+	return float32(suggested), nil
+}
+
+// DepletionForecastDays is how many days back ForecastDepletion looks for consumptions.
+const DepletionForecastDays = 30
+
+// ForecastDepletion estimates when the material runs out if it keeps being used at the rate
+// of the last DepletionForecastDays, or since its creation when it is younger.
+// Like SuggestReorder it counts only the available quantity, as reserved stock can't be consumed.
+// It returns nil when nothing was consumed in that time.
+func (s MaterialService) ForecastDepletion(uid uuid.UUID) (*time.Time, error) {
+	result := <-s.MaterialEventQuery.FindAllByID(uid)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	events, ok := result.Result.([]storage.MaterialEvent)
+	if !ok {
+		return nil, errors.New("Internal server error")
+	}
+
+	if len(events) == 0 {
+		return nil, repository.ErrMaterialNotFound
+	}
+
+	now := domain.MaterialClock.Now()
+
+	start := now.AddDate(0, 0, -DepletionForecastDays)
+	if events[0].CreatedDate.After(start) {
+		start = events[0].CreatedDate
+	}
+
+	consumed := 0.0
+	for _, v := range events {
+		if v.CreatedDate.Before(start) {
+			continue
+		}
+
+		if amount, ok := consumedAmount(v.Event); ok {
+			consumed += float64(amount)
+		}
+	}
+
+	days := now.Sub(start).Hours() / 24
+	if consumed == 0 || days <= 0 {
+		return nil, nil
+	}
+
+	material := repository.NewMaterialFromHistory(events)
+
+	remainingDays := float64(material.AvailableQuantity()) / (consumed / days)
+	depletion := now.Add(time.Duration(remainingDays * 24 * float64(time.Hour)))
+
+	return &depletion, nil
+}
+
+func consumedAmount(event interface{}) (float32, bool) {
+	switch e := event.(type) {
+	case domain.MaterialQuantityConsumed:
+		return e.Amount, true
+	case domain.MaterialLotConsumed:
+		return e.Amount, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	queryInMem "github.com/Tanibox/tania-core/src/assets/query/inmemory"
+	"github.com/Tanibox/tania-core/src/assets/repository/inmemory"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdjustPricesByPercent(t *testing.T) {
+	// Given
+	repo := inmemory.NewMaterialRepositoryInMemory(storage.CreateMaterialEventStorage())
+	s := MaterialService{MaterialRepo: repo}
+
+	mta, _ := domain.CreateMaterialTypeAgrochemical(domain.ChemicalTypeFertilizer)
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	npk, _ := domain.CreateMaterial("NPK", "12.50", domain.MoneyEUR, mta, 10, domain.MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+	urea, _ := domain.CreateMaterial("Urea", "3.35", domain.MoneyEUR, mta, 10, domain.MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+	seed, _ := domain.CreateMaterial("Bayam", "2", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	for _, m := range []*domain.Material{npk, urea, seed} {
+		<-repo.Save(m, m.BaseVersion())
+	}
+
+	// When
+	materials, err := s.AdjustPricesByPercent(domain.MaterialTypeAgrochemicalCode, 10)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Len(t, materials, 2)
+
+	prices := map[string]domain.PricePerUnit{}
+	for _, m := range materials {
+		prices[m.Name] = m.PricePerUnit
+		assert.Len(t, m.UncommittedChanges, 1)
+	}
+
+	assert.Equal(t, domain.PricePerUnit{Amount: "13.75", CurrencyCode: domain.MoneyEUR}, prices["NPK"])
+	assert.Equal(t, domain.PricePerUnit{Amount: "3.69", CurrencyCode: domain.MoneyEUR}, prices["Urea"])
+}
+
+func TestSuggestReorder(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	s := MaterialService{MaterialEventQuery: queryInMem.NewMaterialEventQueryInMemory(materialEventStorage)}
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 30, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	fresh, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 30, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	created := time.Date(2026, time.March, 1, 8, 0, 0, 0, time.UTC)
+	materialEventStorage.MaterialEvents = []storage.MaterialEvent{
+		{MaterialUID: material.UID, Version: 1, CreatedDate: created, Event: material.UncommittedChanges[0]},
+		{MaterialUID: material.UID, Version: 2, CreatedDate: created.AddDate(0, 0, 5), Event: domain.MaterialQuantityConsumed{MaterialUID: material.UID, Amount: 10}},
+		{MaterialUID: material.UID, Version: 3, CreatedDate: created.AddDate(0, 0, 10), Event: domain.MaterialQuantityConsumed{MaterialUID: material.UID, Amount: 10}},
+		{MaterialUID: fresh.UID, Version: 1, CreatedDate: created, Event: fresh.UncommittedChanges[0]},
+	}
+
+	// When
+	suggested, err1 := s.SuggestReorder(material.UID, 7)
+	covered, err2 := s.SuggestReorder(material.UID, 3)
+	_, err3 := s.SuggestReorder(fresh.UID, 7)
+
+	// Then
+	// 20 packets in 10 days is 2 a day, 14 for the lead time, 10 are still available
+	assert.Nil(t, err1)
+	assert.Equal(t, float32(4), suggested)
+	assert.Nil(t, err2)
+	assert.Equal(t, float32(0), covered)
+	assert.Equal(t, errors.New("insufficient consumption history"), err3)
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestForecastDepletion(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	s := MaterialService{MaterialEventQuery: queryInMem.NewMaterialEventQueryInMemory(materialEventStorage)}
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 40, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	unused, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 30, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	reserved, _ := domain.CreateMaterial("Sawi", "8", domain.MoneyEUR, mts, 40, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	created := time.Date(2026, time.January, 1, 8, 0, 0, 0, time.UTC)
+	now := time.Date(2026, time.March, 11, 8, 0, 0, 0, time.UTC)
+	materialEventStorage.MaterialEvents = []storage.MaterialEvent{
+		{MaterialUID: material.UID, Version: 1, CreatedDate: created, Event: material.UncommittedChanges[0]},
+		{MaterialUID: material.UID, Version: 2, CreatedDate: created.AddDate(0, 0, 1), Event: domain.MaterialQuantityConsumed{MaterialUID: material.UID, Amount: 15}},
+		{MaterialUID: material.UID, Version: 3, CreatedDate: now.AddDate(0, 0, -20), Event: domain.MaterialQuantityConsumed{MaterialUID: material.UID, Amount: 5}},
+		{MaterialUID: material.UID, Version: 4, CreatedDate: now.AddDate(0, 0, -10), Event: domain.MaterialQuantityConsumed{MaterialUID: material.UID, Amount: 5}},
+		{MaterialUID: unused.UID, Version: 1, CreatedDate: created, Event: unused.UncommittedChanges[0]},
+		{MaterialUID: reserved.UID, Version: 1, CreatedDate: created, Event: reserved.UncommittedChanges[0]},
+		{MaterialUID: reserved.UID, Version: 2, CreatedDate: created.AddDate(0, 0, 1), Event: domain.MaterialQuantityConsumed{MaterialUID: reserved.UID, Amount: 15}},
+		{MaterialUID: reserved.UID, Version: 3, CreatedDate: now.AddDate(0, 0, -20), Event: domain.MaterialQuantityConsumed{MaterialUID: reserved.UID, Amount: 5}},
+		{MaterialUID: reserved.UID, Version: 4, CreatedDate: now.AddDate(0, 0, -10), Event: domain.MaterialQuantityConsumed{MaterialUID: reserved.UID, Amount: 5}},
+		{MaterialUID: reserved.UID, Version: 5, CreatedDate: now.AddDate(0, 0, -5), Event: domain.MaterialQuantityReserved{MaterialUID: reserved.UID, Amount: 6}},
+	}
+
+	defaultClock := domain.MaterialClock
+	domain.MaterialClock = fixedClock{now: now}
+	defer func() { domain.MaterialClock = defaultClock }()
+
+	// When
+	depletion, err1 := s.ForecastDepletion(material.UID)
+	never, err2 := s.ForecastDepletion(unused.UID)
+	sooner, err3 := s.ForecastDepletion(reserved.UID)
+
+	// Then
+	// 10 packets in the last 30 days is one every 3 days, the 15 remaining last 45 days
+	assert.Nil(t, err1)
+	assert.Equal(t, now.AddDate(0, 0, 45), *depletion)
+	assert.Nil(t, err2)
+	assert.Nil(t, never)
+	// 6 of the 15 remaining packets are reserved, the 9 available last 27 days
+	assert.Nil(t, err3)
+	assert.Equal(t, now.AddDate(0, 0, 27), *sooner)
+}
+
+func TestArchiveExpired(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := inmemory.NewMaterialRepositoryInMemory(materialEventStorage)
+	s := MaterialService{MaterialRepo: repo}
+
+	now := time.Now().AddDate(0, 2, 0)
+	past := time.Now().AddDate(0, 1, 0)
+	future := time.Now().AddDate(1, 0, 0)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	expired, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, &past, nil, nil, nil, nil, nil)
+	current, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, &future, nil, nil, nil, nil, nil)
+	alreadyArchived, _ := domain.CreateMaterial("Sawi", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, &past, nil, nil, nil, nil, nil)
+	alreadyArchived.Archive()
+	nonPerishable, _ := domain.CreateMaterial("Tray", "2", domain.MoneyEUR, domain.MaterialTypeOther{}, 10, domain.MaterialUnitPieces, &past, nil, nil, nil, nil, nil)
+
+	for _, v := range []*domain.Material{expired, current, alreadyArchived, nonPerishable} {
+		assert.Nil(t, <-repo.Save(v, v.BaseVersion()))
+	}
+
+	// When
+	count, err := s.ArchiveExpired(now)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	result := <-repo.FindByID(expired.UID)
+	assert.True(t, result.Result.(*domain.Material).Archived)
+
+	result = <-repo.FindByID(current.UID)
+	assert.False(t, result.Result.(*domain.Material).Archived)
+	assert.Equal(t, 1, result.Result.(*domain.Material).Version)
+
+	result = <-repo.FindByID(nonPerishable.UID)
+	assert.False(t, result.Result.(*domain.Material).Archived)
+}
+
+func TestSaveMaterialPublishesCommittedEvents(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	publisher := &RecordingEventPublisher{}
+	s := MaterialService{
+		MaterialRepo:   inmemory.NewMaterialRepositoryInMemory(materialEventStorage),
+		EventPublisher: publisher,
+	}
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.ConsumeQuantity(5)
+
+	// When
+	err := s.SaveMaterial(material)
+
+	// Then
+	assert.Nil(t, err)
+
+	committed := []interface{}{}
+	for _, v := range materialEventStorage.MaterialEvents {
+		committed = append(committed, v.Event)
+	}
+	assert.Len(t, publisher.Events(), 2)
+	assert.Equal(t, committed, publisher.Events())
+
+	// When
+	stale := material.Clone()
+	material.ConsumeQuantity(1)
+	assert.Nil(t, s.SaveMaterial(material))
+
+	err = stale.ConsumeQuantity(100)
+	stale.ConsumeQuantity(2)
+	err2 := s.SaveMaterial(stale)
+
+	// Then
+	assert.NotNil(t, err)
+	assert.NotNil(t, err2)
+	assert.Len(t, publisher.Events(), 3)
+}
+
+func TestFindOrSaveMaterialPublishesOnlyNewMaterials(t *testing.T) {
+	// Given
+	publisher := &RecordingEventPublisher{}
+	s := MaterialService{
+		MaterialRepo:   inmemory.NewMaterialRepositoryInMemory(storage.CreateMaterialEventStorage()),
+		EventPublisher: publisher,
+	}
+
+	externalID := "seed-import-001"
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, &externalID)
+	retry, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, &externalID)
+
+	// When
+	saved, err := s.FindOrSaveMaterial(material)
+	savedRetry, errRetry := s.FindOrSaveMaterial(retry)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, errRetry)
+	assert.Equal(t, material.UID, saved.UID)
+	assert.Equal(t, material.UID, savedRetry.UID)
+	assert.Len(t, publisher.Events(), 1)
+}
+
+func TestSubscriberRegistry(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	registry := &SubscriberRegistry{}
+	s := MaterialService{
+		MaterialRepo:   inmemory.NewMaterialRepositoryInMemory(materialEventStorage),
+		EventPublisher: registry,
+	}
+
+	received := []interface{}{}
+	storedWhenNotified := []int{}
+	registry.Subscribe(func(event interface{}) { panic("webhook is down") })
+	unsubscribe := registry.Subscribe(func(event interface{}) {
+		received = append(received, event)
+		storedWhenNotified = append(storedWhenNotified, len(materialEventStorage.MaterialEvents))
+	})
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	material.ConsumeQuantity(5)
+	events := append([]interface{}(nil), material.UncommittedChanges...)
+
+	// When
+	err := s.SaveMaterial(material)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, events, received)
+	assert.Equal(t, []int{2, 2}, storedWhenNotified)
+
+	// When
+	unsubscribe()
+	material.ConsumeQuantity(1)
+	err = s.SaveMaterial(material)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Len(t, received, 2)
+}
@@ -1,6 +1,10 @@
 package inmemory
 
 import (
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/Tanibox/tania-core/src/assets/query"
 	"github.com/Tanibox/tania-core/src/assets/storage"
 	uuid "github.com/satori/go.uuid"
@@ -14,7 +18,7 @@ func NewMaterialReadQueryInMemory(s *storage.MaterialReadStorage) query.Material
 	return &MaterialReadQueryInMemory{Storage: s}
 }
 
-func (q *MaterialReadQueryInMemory) FindAll(materialType, materialTypeDetail string, page, limit int) <-chan query.QueryResult {
+func (q *MaterialReadQueryInMemory) FindAll(materialType, materialTypeDetail string, includeArchived bool, page, limit int) <-chan query.QueryResult {
 	result := make(chan query.QueryResult)
 
 	go func() {
@@ -23,6 +27,10 @@ func (q *MaterialReadQueryInMemory) FindAll(materialType, materialTypeDetail str
 
 		materials := []storage.MaterialRead{}
 		for _, val := range q.Storage.MaterialReadMap {
+			if val.Archived && !includeArchived {
+				continue
+			}
+
 			materials = append(materials, val)
 		}
 
@@ -34,14 +42,19 @@ func (q *MaterialReadQueryInMemory) FindAll(materialType, materialTypeDetail str
 	return result
 }
 
-func (q MaterialReadQueryInMemory) CountAll(materialType, materialTypeDetail string) <-chan query.QueryResult {
+func (q MaterialReadQueryInMemory) CountAll(materialType, materialTypeDetail string, includeArchived bool) <-chan query.QueryResult {
 	result := make(chan query.QueryResult)
 
 	go func() {
 		q.Storage.Lock.RLock()
 		defer q.Storage.Lock.RUnlock()
 
-		total := len(q.Storage.MaterialReadMap)
+		total := 0
+		for _, val := range q.Storage.MaterialReadMap {
+			if !val.Archived || includeArchived {
+				total++
+			}
+		}
 
 		result <- query.QueryResult{Result: total}
 
@@ -72,3 +85,141 @@ func (q *MaterialReadQueryInMemory) FindByID(materialUID uuid.UUID) <-chan query
 
 	return result
 }
+
+func (q *MaterialReadQueryInMemory) FindByType(materialTypeCode string) <-chan query.QueryResult {
+	result := make(chan query.QueryResult)
+
+	go func() {
+		q.Storage.Lock.RLock()
+		defer q.Storage.Lock.RUnlock()
+
+		materials := []storage.MaterialRead{}
+		for _, val := range q.Storage.MaterialReadMap {
+			if !val.Archived && val.Type != nil && val.Type.Code() == materialTypeCode {
+				materials = append(materials, val)
+			}
+		}
+
+		sortMaterialReadsByCreatedDate(materials)
+
+		result <- query.QueryResult{Result: materials}
+
+		close(result)
+	}()
+
+	return result
+}
+
+func (q *MaterialReadQueryInMemory) FindExpiringBefore(cutoff time.Time) <-chan query.QueryResult {
+	result := make(chan query.QueryResult)
+
+	go func() {
+		q.Storage.Lock.RLock()
+		defer q.Storage.Lock.RUnlock()
+
+		materials := []storage.MaterialRead{}
+		for _, val := range q.Storage.MaterialReadMap {
+			if !val.Archived && val.ExpirationDate != nil && !val.ExpirationDate.After(cutoff) {
+				materials = append(materials, val)
+			}
+		}
+
+		sort.Slice(materials, func(i, j int) bool {
+			return materials[i].ExpirationDate.Before(*materials[j].ExpirationDate)
+		})
+
+		result <- query.QueryResult{Result: materials}
+
+		close(result)
+	}()
+
+	return result
+}
+
+func (q *MaterialReadQueryInMemory) FindAllPaged(includeArchived bool, offset, limit int) <-chan query.QueryResult {
+	result := make(chan query.QueryResult)
+
+	go func() {
+		q.Storage.Lock.RLock()
+		defer q.Storage.Lock.RUnlock()
+
+		offset, limit := query.NormalizeOffsetLimit(offset, limit)
+
+		materials := []storage.MaterialRead{}
+		for _, val := range q.Storage.MaterialReadMap {
+			if val.Archived && !includeArchived {
+				continue
+			}
+
+			materials = append(materials, val)
+		}
+
+		sort.Slice(materials, func(i, j int) bool {
+			if materials[i].Name != materials[j].Name {
+				return materials[i].Name < materials[j].Name
+			}
+
+			return materials[i].UID.String() < materials[j].UID.String()
+		})
+
+		total := len(materials)
+
+		if offset > total {
+			offset = total
+		}
+
+		end := offset + limit
+		if end > total {
+			end = total
+		}
+
+		result <- query.QueryResult{Result: query.MaterialPageQueryResult{
+			Materials: materials[offset:end],
+			Total:     total,
+		}}
+
+		close(result)
+	}()
+
+	return result
+}
+
+func (q *MaterialReadQueryInMemory) Search(keyword string) <-chan query.QueryResult {
+	result := make(chan query.QueryResult)
+
+	go func() {
+		q.Storage.Lock.RLock()
+		defer q.Storage.Lock.RUnlock()
+
+		keyword = strings.ToLower(keyword)
+
+		materials := []storage.MaterialRead{}
+		for _, val := range q.Storage.MaterialReadMap {
+			if val.Archived {
+				continue
+			}
+
+			if strings.Contains(strings.ToLower(val.Name), keyword) ||
+				(val.Notes != nil && strings.Contains(strings.ToLower(*val.Notes), keyword)) {
+				materials = append(materials, val)
+			}
+		}
+
+		sort.Slice(materials, func(i, j int) bool {
+			return materials[i].Name < materials[j].Name
+		})
+
+		result <- query.QueryResult{Result: materials}
+
+		close(result)
+	}()
+
+	return result
+}
+
+// sortMaterialReadsByCreatedDate sorts newest first, like the SQL queries do.
+func sortMaterialReadsByCreatedDate(materials []storage.MaterialRead) {
+	sort.Slice(materials, func(i, j int) bool {
+		return materials[i].CreatedDate.After(materials[j].CreatedDate)
+	})
+}
@@ -0,0 +1,192 @@
+package inmemory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/query"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func createMaterialRead(name string, materialType storage.MaterialType, createdDate time.Time) storage.MaterialRead {
+	uid, _ := uuid.NewV4()
+
+	return storage.MaterialRead{
+		UID:         uid,
+		Name:        name,
+		Type:        materialType,
+		CreatedDate: createdDate,
+	}
+}
+
+func TestMaterialReadQueryFindByType(t *testing.T) {
+	// Given
+	materialReadStorage := storage.CreateMaterialReadStorage()
+	q := NewMaterialReadQueryInMemory(materialReadStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	mta, _ := domain.CreateMaterialTypeAgrochemical(domain.ChemicalTypeFertilizer)
+	now := time.Now()
+
+	seed1 := createMaterialRead("Bayam Lu Hsieh", mts, now.Add(-time.Hour))
+	seed2 := createMaterialRead("Tomato Cherry", mts, now)
+	chemical := createMaterialRead("Organic Compost", mta, now)
+
+	for _, v := range []storage.MaterialRead{seed1, seed2, chemical} {
+		materialReadStorage.MaterialReadMap[v.UID] = v
+	}
+
+	// When
+	seeds := <-q.FindByType(domain.MaterialTypeSeedCode)
+	plants := <-q.FindByType(domain.MaterialTypePlantCode)
+
+	// Then
+	assert.Nil(t, seeds.Error)
+	assert.Equal(t, []storage.MaterialRead{seed2, seed1}, seeds.Result)
+
+	assert.Nil(t, plants.Error)
+	assert.Equal(t, []storage.MaterialRead{}, plants.Result)
+}
+
+func TestMaterialReadQueryFindExpiringBefore(t *testing.T) {
+	// Given
+	materialReadStorage := storage.CreateMaterialReadStorage()
+	q := NewMaterialReadQueryInMemory(materialReadStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	now := time.Now()
+	expired := now.AddDate(0, 0, -3)
+	soon := now.AddDate(0, 0, 5)
+	farFuture := now.AddDate(1, 0, 0)
+
+	expiredMaterial := createMaterialRead("Bayam Lu Hsieh", mts, now)
+	expiredMaterial.ExpirationDate = &expired
+	soonMaterial := createMaterialRead("Tomato Cherry", mts, now)
+	soonMaterial.ExpirationDate = &soon
+	farFutureMaterial := createMaterialRead("Chili Rawit", mts, now)
+	farFutureMaterial.ExpirationDate = &farFuture
+	noDateMaterial := createMaterialRead("Kangkung", mts, now)
+
+	for _, v := range []storage.MaterialRead{soonMaterial, farFutureMaterial, noDateMaterial, expiredMaterial} {
+		materialReadStorage.MaterialReadMap[v.UID] = v
+	}
+
+	// When
+	result := <-q.FindExpiringBefore(now.AddDate(0, 0, 7))
+
+	// Then
+	assert.Nil(t, result.Error)
+	assert.Equal(t, []storage.MaterialRead{expiredMaterial, soonMaterial}, result.Result)
+}
+
+func TestMaterialReadQueryFindAllPaged(t *testing.T) {
+	// Given
+	materialReadStorage := storage.CreateMaterialReadStorage()
+	q := NewMaterialReadQueryInMemory(materialReadStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	now := time.Now()
+
+	names := []string{"Eggplant", "Basil", "Daikon", "Arugula", "Cabbage"}
+	for _, name := range names {
+		m := createMaterialRead(name, mts, now)
+		materialReadStorage.MaterialReadMap[m.UID] = m
+	}
+
+	// When
+	page1 := <-q.FindAllPaged(false, 0, 2)
+	page2 := <-q.FindAllPaged(false, 2, 2)
+	page3 := <-q.FindAllPaged(false, 4, 2)
+	beyond := <-q.FindAllPaged(false, 10, 2)
+	invalid := <-q.FindAllPaged(false, -1, 0)
+
+	// Then
+	pageNames := func(r query.QueryResult) []string {
+		names := []string{}
+		for _, v := range r.Result.(query.MaterialPageQueryResult).Materials {
+			names = append(names, v.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Arugula", "Basil"}, pageNames(page1))
+	assert.Equal(t, []string{"Cabbage", "Daikon"}, pageNames(page2))
+	assert.Equal(t, []string{"Eggplant"}, pageNames(page3))
+	assert.Equal(t, []string{}, pageNames(beyond))
+	assert.Equal(t, []string{"Arugula", "Basil", "Cabbage", "Daikon", "Eggplant"}, pageNames(invalid))
+
+	for _, r := range []query.QueryResult{page1, page2, page3, beyond, invalid} {
+		assert.Nil(t, r.Error)
+		assert.Equal(t, 5, r.Result.(query.MaterialPageQueryResult).Total)
+	}
+}
+
+func TestMaterialReadQuerySearch(t *testing.T) {
+	// Given
+	materialReadStorage := storage.CreateMaterialReadStorage()
+	q := NewMaterialReadQueryInMemory(materialReadStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	now := time.Now()
+	notes := "Bought from the Saturday market"
+
+	tomato := createMaterialRead("Tomato Cherry", mts, now)
+	basil := createMaterialRead("Basil Genovese", mts, now)
+	basil.Notes = &notes
+	spinach := createMaterialRead("Spinach", mts, now)
+
+	for _, v := range []storage.MaterialRead{tomato, basil, spinach} {
+		materialReadStorage.MaterialReadMap[v.UID] = v
+	}
+
+	// When
+	byName := <-q.Search("CHERRY")
+	byNotes := <-q.Search("saturday")
+	noMatch := <-q.Search("cucumber")
+
+	// Then
+	assert.Nil(t, byName.Error)
+	assert.Equal(t, []storage.MaterialRead{tomato}, byName.Result)
+	assert.Nil(t, byNotes.Error)
+	assert.Equal(t, []storage.MaterialRead{basil}, byNotes.Result)
+	assert.Nil(t, noMatch.Error)
+	assert.Equal(t, []storage.MaterialRead{}, noMatch.Result)
+}
+
+func TestMaterialReadQueryArchived(t *testing.T) {
+	// Given
+	materialReadStorage := storage.CreateMaterialReadStorage()
+	q := NewMaterialReadQueryInMemory(materialReadStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	active := createMaterialRead("Bayam Lu Hsieh", mts, time.Now())
+	archived := createMaterialRead("Kangkung Bangkok", mts, time.Now())
+	archived.Archived = true
+
+	for _, v := range []storage.MaterialRead{active, archived} {
+		materialReadStorage.MaterialReadMap[v.UID] = v
+	}
+
+	// When
+	defaultList := <-q.FindAll("", "", false, 0, 0)
+	fullList := <-q.FindAll("", "", true, 0, 0)
+	defaultCount := <-q.CountAll("", "", false)
+	fullCount := <-q.CountAll("", "", true)
+	page := <-q.FindAllPaged(false, 0, 10)
+	byID := <-q.FindByID(archived.UID)
+	byType := <-q.FindByType(domain.MaterialTypeSeedCode)
+	search := <-q.Search("kangkung")
+
+	// Then
+	assert.Equal(t, []storage.MaterialRead{active}, defaultList.Result)
+	assert.Len(t, fullList.Result, 2)
+	assert.Equal(t, 1, defaultCount.Result)
+	assert.Equal(t, 2, fullCount.Result)
+	assert.Equal(t, 1, page.Result.(query.MaterialPageQueryResult).Total)
+	assert.Equal(t, archived, byID.Result)
+	assert.Equal(t, []storage.MaterialRead{active}, byType.Result)
+	assert.Equal(t, []storage.MaterialRead{}, search.Result)
+}
@@ -0,0 +1,100 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	uuid "github.com/satori/go.uuid"
+)
+
+// MaterialReadRow is a MATERIAL_READ row, once the SQL driver decoded the columns
+// it stores in its own format, the UID and the dates.
+type MaterialReadRow struct {
+	UID            uuid.UUID
+	Name           string
+	PricePerUnit   string
+	CurrencyCode   string
+	Type           string
+	TypeData       string
+	Quantity       float32
+	QuantityUnit   string
+	ExpirationDate *time.Time
+	Notes          *string
+	ProducedBy     *string
+	IsExpense      *bool
+	CreatedDate    time.Time
+	Archived       bool
+}
+
+// MaterialRead maps the row to the material read model.
+func (r MaterialReadRow) MaterialRead() (storage.MaterialRead, error) {
+	pricePerUnit, err := domain.CreatePricePerUnit(r.PricePerUnit, r.CurrencyCode)
+	if err != nil {
+		return storage.MaterialRead{}, err
+	}
+
+	materialType, err := CreateMaterialReadType(r.Type, r.TypeData)
+	if err != nil {
+		return storage.MaterialRead{}, err
+	}
+
+	qtyUnit := domain.GetMaterialQuantityUnit(r.Type, r.QuantityUnit)
+	if qtyUnit == (domain.MaterialQuantityUnit{}) {
+		return storage.MaterialRead{}, errors.New("Invalid quantity unit")
+	}
+
+	return storage.MaterialRead{
+		UID:          r.UID,
+		Name:         r.Name,
+		PricePerUnit: storage.PricePerUnit(pricePerUnit),
+		Type:         materialType,
+		Quantity: storage.MaterialQuantity{
+			Unit:  qtyUnit,
+			Value: r.Quantity,
+		},
+		ExpirationDate: r.ExpirationDate,
+		Notes:          r.Notes,
+		ProducedBy:     r.ProducedBy,
+		IsExpense:      r.IsExpense,
+		CreatedDate:    r.CreatedDate,
+		Archived:       r.Archived,
+	}, nil
+}
+
+// SplitPlantTypeData splits the TYPE_DATA of a plant into its plant type code
+// and its packaging, which is empty on rows written before packagings existed.
+func SplitPlantTypeData(typeData string) (string, string) {
+	parts := strings.SplitN(typeData, ":", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+
+	return parts[0], parts[1]
+}
+
+// CreateMaterialReadType builds the material type from the TYPE and TYPE_DATA columns.
+func CreateMaterialReadType(materialTypeCode, typeData string) (storage.MaterialType, error) {
+	switch materialTypeCode {
+	case domain.MaterialTypePlantCode:
+		return domain.CreateMaterialTypePlantWithPackaging(SplitPlantTypeData(typeData))
+	case domain.MaterialTypeSeedCode:
+		return domain.CreateMaterialTypeSeed(typeData)
+	case domain.MaterialTypeGrowingMediumCode:
+		return domain.MaterialTypeGrowingMedium{}, nil
+	case domain.MaterialTypeAgrochemicalCode:
+		return domain.CreateMaterialTypeAgrochemical(typeData)
+	case domain.MaterialTypeLabelAndCropSupportCode:
+		return domain.MaterialTypeLabelAndCropSupport{}, nil
+	case domain.MaterialTypeSeedingContainerCode:
+		return domain.CreateMaterialTypeSeedingContainer(typeData)
+	case domain.MaterialTypePostHarvestSupplyCode:
+		return domain.MaterialTypePostHarvestSupply{}, nil
+	case domain.MaterialTypeOtherCode:
+		return domain.MaterialTypeOther{}, nil
+	}
+
+	return nil, errors.New("Invalid material type")
+}
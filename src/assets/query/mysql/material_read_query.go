@@ -2,11 +2,9 @@ package mysql
 
 import (
 	"database/sql"
-	"errors"
 	"strings"
 	"time"
 
-	"github.com/Tanibox/tania-core/src/assets/domain"
 	"github.com/Tanibox/tania-core/src/assets/query"
 	"github.com/Tanibox/tania-core/src/assets/storage"
 	"github.com/Tanibox/tania-core/src/helper/paginationhelper"
@@ -21,6 +19,10 @@ func NewMaterialReadQueryMysql(db *sql.DB) query.MaterialReadQuery {
 	return MaterialReadQueryMysql{DB: db}
 }
 
+// materialReadColumns are the MATERIAL_READ columns materialReadResult is scanned from, in scan order.
+const materialReadColumns = "UID, NAME, PRICE_PER_UNIT, CURRENCY_CODE, TYPE, TYPE_DATA, QUANTITY, QUANTITY_UNIT, " +
+	"EXPIRATION_DATE, NOTES, PRODUCED_BY, IS_EXPENSE, CREATED_DATE, IS_ARCHIVED"
+
 type materialReadResult struct {
 	UID            []byte
 	Name           string
@@ -33,40 +35,48 @@ type materialReadResult struct {
 	ExpirationDate sql.NullString
 	Notes          sql.NullString
 	ProducedBy     sql.NullString
+	IsExpense      sql.NullBool
 	CreatedDate    time.Time
+	Archived       bool
 }
 
-func (q MaterialReadQueryMysql) FindAll(materialType, materialTypeDetail string, page, limit int) <-chan query.QueryResult {
+func (q MaterialReadQueryMysql) FindAll(materialType, materialTypeDetail string, includeArchived bool, page, limit int) <-chan query.QueryResult {
 	result := make(chan query.QueryResult)
 
 	go func() {
-		materialReads := []storage.MaterialRead{}
-		rowsData := materialReadResult{}
 		var params []interface{}
 
-		sql := "SELECT * FROM MATERIAL_READ WHERE 1 = 1"
+		sql := "SELECT " + materialReadColumns + " FROM MATERIAL_READ WHERE 1 = 1"
+
+		if !includeArchived {
+			sql += " AND IS_ARCHIVED = 0"
+		}
 
 		if materialType != "" {
 			t := strings.Split(materialType, ",")
 
-			sql += " AND TYPE = ?"
+			sql += " AND (TYPE = ?"
 			params = append(params, t[0])
 
 			for _, v := range t[1:] {
 				sql += " OR TYPE = ?"
 				params = append(params, v)
 			}
+
+			sql += ")"
 		}
 		if materialTypeDetail != "" {
 			t := strings.Split(materialTypeDetail, ",")
 
-			sql += " AND TYPE_DATA = ?"
+			sql += " AND (TYPE_DATA = ?"
 			params = append(params, t[0])
 
 			for _, v := range t[1:] {
 				sql += " OR TYPE_DATA = ?"
 				params = append(params, v)
 			}
+
+			sql += ")"
 		}
 
 		sql += " ORDER BY CREATED_DATE DESC"
@@ -77,114 +87,11 @@ func (q MaterialReadQueryMysql) FindAll(materialType, materialTypeDetail string,
 			params = append(params, limit, offset)
 		}
 
-		rows, err := q.DB.Query(sql, params...)
+		materialReads, err := q.findMaterialReads(sql, params...)
 		if err != nil {
 			result <- query.QueryResult{Error: err}
-		}
-
-		for rows.Next() {
-			err = rows.Scan(
-				&rowsData.UID,
-				&rowsData.Name,
-				&rowsData.PricePerUnit,
-				&rowsData.CurrencyCode,
-				&rowsData.Type,
-				&rowsData.TypeData,
-				&rowsData.Quantity,
-				&rowsData.QuantityUnit,
-				&rowsData.ExpirationDate,
-				&rowsData.Notes,
-				&rowsData.ProducedBy,
-				&rowsData.CreatedDate,
-			)
-
-			if err != nil {
-				result <- query.QueryResult{Error: err}
-			}
-
-			materialUID, err := uuid.FromBytes(rowsData.UID)
-			if err != nil {
-				result <- query.QueryResult{Error: err}
-			}
-
-			var mExpDate *time.Time
-			if rowsData.ExpirationDate.Valid && rowsData.ExpirationDate.String != "" {
-				date, err := time.Parse("2006-01-02 15:04:05", rowsData.ExpirationDate.String)
-				if err != nil {
-					result <- query.QueryResult{Error: err}
-				}
-
-				mExpDate = &date
-			}
-
-			pricePerUnit, err := domain.CreatePricePerUnit(rowsData.PricePerUnit, rowsData.CurrencyCode)
-			if err != nil {
-				result <- query.QueryResult{Error: err}
-			}
-
-			var materialType storage.MaterialType
-			switch rowsData.Type {
-			case domain.MaterialTypePlantCode:
-				materialType, err = domain.CreateMaterialTypePlant(rowsData.TypeData)
-				if err != nil {
-					result <- query.QueryResult{Error: err}
-				}
-			case domain.MaterialTypeSeedCode:
-				materialType, err = domain.CreateMaterialTypeSeed(rowsData.TypeData)
-				if err != nil {
-					result <- query.QueryResult{Error: err}
-				}
-			case domain.MaterialTypeGrowingMediumCode:
-				materialType = domain.MaterialTypeGrowingMedium{}
-			case domain.MaterialTypeAgrochemicalCode:
-				materialType, err = domain.CreateMaterialTypeAgrochemical(rowsData.TypeData)
-				if err != nil {
-					result <- query.QueryResult{Error: err}
-				}
-			case domain.MaterialTypeLabelAndCropSupportCode:
-				materialType = domain.MaterialTypeLabelAndCropSupport{}
-			case domain.MaterialTypeSeedingContainerCode:
-				materialType, err = domain.CreateMaterialTypeSeedingContainer(rowsData.TypeData)
-				if err != nil {
-					result <- query.QueryResult{Error: err}
-				}
-			case domain.MaterialTypePostHarvestSupplyCode:
-				materialType = domain.MaterialTypePostHarvestSupply{}
-			case domain.MaterialTypeOtherCode:
-				materialType = domain.MaterialTypeOther{}
-			default:
-				result <- query.QueryResult{Error: errors.New("Invalid material type")}
-			}
-
-			qtyUnit := domain.GetMaterialQuantityUnit(rowsData.Type, rowsData.QuantityUnit)
-			if qtyUnit == (domain.MaterialQuantityUnit{}) {
-				result <- query.QueryResult{Error: errors.New("Invalid quantity unit")}
-			}
-
-			var notes *string
-			if rowsData.Notes.Valid {
-				notes = &rowsData.Notes.String
-			}
-
-			var producedBy *string
-			if rowsData.ProducedBy.Valid {
-				producedBy = &rowsData.ProducedBy.String
-			}
-
-			materialReads = append(materialReads, storage.MaterialRead{
-				UID:          materialUID,
-				Name:         rowsData.Name,
-				PricePerUnit: storage.PricePerUnit(pricePerUnit),
-				Type:         materialType,
-				Quantity: storage.MaterialQuantity{
-					Unit:  qtyUnit,
-					Value: rowsData.Quantity,
-				},
-				ExpirationDate: mExpDate,
-				Notes:          notes,
-				ProducedBy:     producedBy,
-				CreatedDate:    rowsData.CreatedDate,
-			})
+			close(result)
+			return
 		}
 
 		result <- query.QueryResult{Result: materialReads}
@@ -194,7 +101,7 @@ func (q MaterialReadQueryMysql) FindAll(materialType, materialTypeDetail string,
 	return result
 }
 
-func (q MaterialReadQueryMysql) CountAll(materialType, materialTypeDetail string) <-chan query.QueryResult {
+func (q MaterialReadQueryMysql) CountAll(materialType, materialTypeDetail string, includeArchived bool) <-chan query.QueryResult {
 	result := make(chan query.QueryResult)
 
 	go func() {
@@ -203,27 +110,35 @@ func (q MaterialReadQueryMysql) CountAll(materialType, materialTypeDetail string
 
 		sql := "SELECT COUNT(UID) FROM MATERIAL_READ WHERE 1 = 1"
 
+		if !includeArchived {
+			sql += " AND IS_ARCHIVED = 0"
+		}
+
 		if materialType != "" {
 			t := strings.Split(materialType, ",")
 
-			sql += " AND TYPE = ?"
+			sql += " AND (TYPE = ?"
 			params = append(params, t[0])
 
 			for _, v := range t[1:] {
 				sql += " OR TYPE = ?"
 				params = append(params, v)
 			}
+
+			sql += ")"
 		}
 		if materialTypeDetail != "" {
 			t := strings.Split(materialTypeDetail, ",")
 
-			sql += " AND TYPE_DATA = ?"
+			sql += " AND (TYPE_DATA = ?"
 			params = append(params, t[0])
 
 			for _, v := range t[1:] {
 				sql += " OR TYPE_DATA = ?"
 				params = append(params, v)
 			}
+
+			sql += ")"
 		}
 
 		err := q.DB.QueryRow(sql, params...).Scan(&total)
@@ -242,10 +157,137 @@ func (q MaterialReadQueryMysql) FindByID(materialUID uuid.UUID) <-chan query.Que
 	result := make(chan query.QueryResult)
 
 	go func() {
+		materialReads, err := q.findMaterialReads("SELECT "+materialReadColumns+" FROM MATERIAL_READ WHERE UID = ?", materialUID.Bytes())
+		if err != nil {
+			result <- query.QueryResult{Error: err}
+			close(result)
+			return
+		}
+
+		// A material that isn't found is an empty MaterialRead, not an error
 		materialRead := storage.MaterialRead{}
+		if len(materialReads) > 0 {
+			materialRead = materialReads[0]
+		}
+
+		result <- query.QueryResult{Result: materialRead}
+		close(result)
+	}()
+
+	return result
+}
+
+func (q MaterialReadQueryMysql) FindByType(materialTypeCode string) <-chan query.QueryResult {
+	result := make(chan query.QueryResult)
+
+	go func() {
+		materialReads, err := q.findMaterialReads("SELECT "+materialReadColumns+" FROM MATERIAL_READ WHERE IS_ARCHIVED = 0 AND TYPE = ? ORDER BY CREATED_DATE DESC", materialTypeCode)
+		if err != nil {
+			result <- query.QueryResult{Error: err}
+			close(result)
+			return
+		}
+
+		result <- query.QueryResult{Result: materialReads}
+		close(result)
+	}()
+
+	return result
+}
+
+func (q MaterialReadQueryMysql) FindExpiringBefore(cutoff time.Time) <-chan query.QueryResult {
+	result := make(chan query.QueryResult)
+
+	go func() {
+		materialReads, err := q.findMaterialReads("SELECT "+materialReadColumns+` FROM MATERIAL_READ
+			WHERE IS_ARCHIVED = 0 AND EXPIRATION_DATE IS NOT NULL AND EXPIRATION_DATE <= ?
+			ORDER BY EXPIRATION_DATE ASC`, cutoff)
+		if err != nil {
+			result <- query.QueryResult{Error: err}
+			close(result)
+			return
+		}
+
+		result <- query.QueryResult{Result: materialReads}
+		close(result)
+	}()
+
+	return result
+}
+
+func (q MaterialReadQueryMysql) FindAllPaged(includeArchived bool, offset, limit int) <-chan query.QueryResult {
+	result := make(chan query.QueryResult)
+
+	go func() {
+		offset, limit := query.NormalizeOffsetLimit(offset, limit)
+
+		where := ""
+		if !includeArchived {
+			where = " WHERE IS_ARCHIVED = 0"
+		}
+
+		total := 0
+		err := q.DB.QueryRow("SELECT COUNT(UID) FROM MATERIAL_READ" + where).Scan(&total)
+		if err != nil {
+			result <- query.QueryResult{Error: err}
+			close(result)
+			return
+		}
+
+		materialReads, err := q.findMaterialReads("SELECT "+materialReadColumns+" FROM MATERIAL_READ"+where+" ORDER BY NAME ASC, UID ASC LIMIT ? OFFSET ?", limit, offset)
+		if err != nil {
+			result <- query.QueryResult{Error: err}
+			close(result)
+			return
+		}
+
+		result <- query.QueryResult{Result: query.MaterialPageQueryResult{
+			Materials: materialReads,
+			Total:     total,
+		}}
+		close(result)
+	}()
+
+	return result
+}
+
+func (q MaterialReadQueryMysql) Search(keyword string) <-chan query.QueryResult {
+	result := make(chan query.QueryResult)
+
+	go func() {
+		pattern := "%" + strings.ToLower(query.EscapeLikePattern(keyword)) + "%"
+
+		materialReads, err := q.findMaterialReads("SELECT "+materialReadColumns+` FROM MATERIAL_READ
+			WHERE IS_ARCHIVED = 0 AND (LOWER(NAME) LIKE ? ESCAPE '\\' OR LOWER(NOTES) LIKE ? ESCAPE '\\')
+			ORDER BY NAME ASC`, pattern, pattern)
+		if err != nil {
+			result <- query.QueryResult{Error: err}
+			close(result)
+			return
+		}
+
+		result <- query.QueryResult{Result: materialReads}
+		close(result)
+	}()
+
+	return result
+}
+
+// findMaterialReads runs a SELECT of materialReadColumns on MATERIAL_READ and maps every row.
+// It always returns a non-nil slice when there is no error.
+func (q MaterialReadQueryMysql) findMaterialReads(sql string, params ...interface{}) ([]storage.MaterialRead, error) {
+	materialReads := []storage.MaterialRead{}
+
+	rows, err := q.DB.Query(sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
 		rowsData := materialReadResult{}
 
-		err := q.DB.QueryRow(`SELECT * FROM MATERIAL_READ WHERE UID = ?`, materialUID.Bytes()).Scan(
+		err = rows.Scan(
 			&rowsData.UID,
 			&rowsData.Name,
 			&rowsData.PricePerUnit,
@@ -257,104 +299,62 @@ func (q MaterialReadQueryMysql) FindByID(materialUID uuid.UUID) <-chan query.Que
 			&rowsData.ExpirationDate,
 			&rowsData.Notes,
 			&rowsData.ProducedBy,
+			&rowsData.IsExpense,
 			&rowsData.CreatedDate,
+			&rowsData.Archived,
 		)
-
-		if err != nil && err != sql.ErrNoRows {
-			result <- query.QueryResult{Error: err}
-		}
-
-		if err == sql.ErrNoRows {
-			result <- query.QueryResult{Result: materialRead}
+		if err != nil {
+			return nil, err
 		}
 
 		materialUID, err := uuid.FromBytes(rowsData.UID)
 		if err != nil {
-			result <- query.QueryResult{Error: err}
+			return nil, err
 		}
 
 		var mExpDate *time.Time
 		if rowsData.ExpirationDate.Valid && rowsData.ExpirationDate.String != "" {
 			date, err := time.Parse("2006-01-02 15:04:05", rowsData.ExpirationDate.String)
 			if err != nil {
-				result <- query.QueryResult{Error: err}
+				return nil, err
 			}
 
 			mExpDate = &date
 		}
 
-		pricePerUnit, err := domain.CreatePricePerUnit(rowsData.PricePerUnit, rowsData.CurrencyCode)
-		if err != nil {
-			result <- query.QueryResult{Error: err}
-		}
-
-		var materialType storage.MaterialType
-		switch rowsData.Type {
-		case domain.MaterialTypePlantCode:
-			materialType, err = domain.CreateMaterialTypePlant(rowsData.TypeData)
-			if err != nil {
-				result <- query.QueryResult{Error: err}
-			}
-		case domain.MaterialTypeSeedCode:
-			materialType, err = domain.CreateMaterialTypeSeed(rowsData.TypeData)
-			if err != nil {
-				result <- query.QueryResult{Error: err}
-			}
-		case domain.MaterialTypeGrowingMediumCode:
-			materialType = domain.MaterialTypeGrowingMedium{}
-		case domain.MaterialTypeAgrochemicalCode:
-			materialType, err = domain.CreateMaterialTypeAgrochemical(rowsData.TypeData)
-			if err != nil {
-				result <- query.QueryResult{Error: err}
-			}
-		case domain.MaterialTypeLabelAndCropSupportCode:
-			materialType = domain.MaterialTypeLabelAndCropSupport{}
-		case domain.MaterialTypeSeedingContainerCode:
-			materialType, err = domain.CreateMaterialTypeSeedingContainer(rowsData.TypeData)
-			if err != nil {
-				result <- query.QueryResult{Error: err}
-			}
-		case domain.MaterialTypePostHarvestSupplyCode:
-			materialType = domain.MaterialTypePostHarvestSupply{}
-		case domain.MaterialTypeOtherCode:
-			materialType = domain.MaterialTypeOther{}
-		default:
-			result <- query.QueryResult{Error: errors.New("Invalid material type")}
-		}
-
-		qtyUnit := domain.GetMaterialQuantityUnit(rowsData.Type, rowsData.QuantityUnit)
-		if qtyUnit == (domain.MaterialQuantityUnit{}) {
-			result <- query.QueryResult{Error: errors.New("Invalid quantity unit")}
+		row := query.MaterialReadRow{
+			UID:            materialUID,
+			Name:           rowsData.Name,
+			PricePerUnit:   rowsData.PricePerUnit,
+			CurrencyCode:   rowsData.CurrencyCode,
+			Type:           rowsData.Type,
+			TypeData:       rowsData.TypeData,
+			Quantity:       rowsData.Quantity,
+			QuantityUnit:   rowsData.QuantityUnit,
+			ExpirationDate: mExpDate,
+			CreatedDate:    rowsData.CreatedDate,
+			Archived:       rowsData.Archived,
 		}
 
-		var notes *string
 		if rowsData.Notes.Valid {
-			notes = &rowsData.Notes.String
+			row.Notes = &rowsData.Notes.String
 		}
 
-		var producedBy *string
 		if rowsData.ProducedBy.Valid {
-			producedBy = &rowsData.ProducedBy.String
+			row.ProducedBy = &rowsData.ProducedBy.String
 		}
 
-		materialRead = storage.MaterialRead{
-			UID:          materialUID,
-			Name:         rowsData.Name,
-			PricePerUnit: storage.PricePerUnit(pricePerUnit),
-			Type:         materialType,
-			Quantity: storage.MaterialQuantity{
-				Unit:  qtyUnit,
-				Value: rowsData.Quantity,
-			},
-			ExpirationDate: mExpDate,
-			Notes:          notes,
-			ProducedBy:     producedBy,
-			CreatedDate:    rowsData.CreatedDate,
+		if rowsData.IsExpense.Valid {
+			row.IsExpense = &rowsData.IsExpense.Bool
 		}
 
-		result <- query.QueryResult{Result: materialRead}
-		close(result)
-	}()
+		materialRead, err := row.MaterialRead()
+		if err != nil {
+			return nil, err
+		}
 
-	return result
+		materialReads = append(materialReads, materialRead)
+	}
+
+	return materialReads, rows.Err()
 }
@@ -1,8 +1,11 @@
 package query
 
 import (
+	"strings"
 	"time"
 
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	"github.com/Tanibox/tania-core/src/helper/paginationhelper"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -45,10 +48,41 @@ type MaterialEventQuery interface {
 	FindAllByID(materialUID uuid.UUID) <-chan QueryResult
 }
 
+// MaterialReadQuery queries the material read model.
+// FindAll, CountAll and FindAllPaged leave archived materials out unless includeArchived is true.
+// FindByType, FindExpiringBefore and Search always leave them out, only FindByID returns them.
 type MaterialReadQuery interface {
-	FindAll(materialType, materialTypeDetail string, page, limit int) <-chan QueryResult
-	CountAll(materialType, materialTypeDetail string) <-chan QueryResult
+	FindAll(materialType, materialTypeDetail string, includeArchived bool, page, limit int) <-chan QueryResult
+	CountAll(materialType, materialTypeDetail string, includeArchived bool) <-chan QueryResult
 	FindByID(materialUID uuid.UUID) <-chan QueryResult
+	FindByType(materialTypeCode string) <-chan QueryResult
+	FindExpiringBefore(cutoff time.Time) <-chan QueryResult
+	FindAllPaged(includeArchived bool, offset, limit int) <-chan QueryResult
+	Search(keyword string) <-chan QueryResult
+}
+
+// MaterialPageQueryResult is one page of materials, ordered by name then UID,
+// with the total number of materials across all pages.
+type MaterialPageQueryResult struct {
+	Materials []storage.MaterialRead
+	Total     int
+}
+
+// EscapeLikePattern escapes the LIKE wildcards in s, using backslash as the escape character
+func EscapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// NormalizeOffsetLimit falls back to offset 0 and the default limit on invalid input
+func NormalizeOffsetLimit(offset, limit int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = paginationhelper.DefaultLimit
+	}
+
+	return offset, limit
 }
 
 type QueryResult struct {
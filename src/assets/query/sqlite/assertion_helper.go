@@ -142,8 +142,9 @@ func makeMaterialType(v interface{}) (domain.MaterialType, error) {
 		mapped2 := mapped["Data"].(map[string]interface{})
 		mapped3 := mapped2["PlantType"].(map[string]interface{})
 		typeCode := mapped3["code"].(string)
+		packaging, _ := mapped2["Packaging"].(string)
 
-		t, err := domain.CreateMaterialTypePlant(typeCode)
+		t, err := domain.CreateMaterialTypePlantWithPackaging(typeCode, packaging)
 		if err != nil {
 			return nil, err
 		}
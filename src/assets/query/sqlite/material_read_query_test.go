@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/query"
+	repoSqlite "github.com/Tanibox/tania-core/src/assets/repository/sqlite"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	_ "github.com/mattn/go-sqlite3"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func openMaterialReadDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every connection to :memory: opens its own empty database
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE "MATERIAL_READ" (
+		"UID" BLOB PRIMARY KEY,
+		"NAME" TEXT,
+		"PRICE_PER_UNIT" TEXT,
+		"CURRENCY_CODE" TEXT,
+		"TYPE" TEXT,
+		"TYPE_DATA" TEXT,
+		"QUANTITY" REAL,
+		"QUANTITY_UNIT" TEXT,
+		"EXPIRATION_DATE" TEXT,
+		"NOTES" TEXT,
+		"PRODUCED_BY" TEXT,
+		"IS_EXPENSE" BOOLEAN,
+		"CREATED_DATE" TEXT,
+		"IS_ARCHIVED" BOOLEAN DEFAULT 0
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func newMaterialRead(name string, materialType domain.MaterialType, quantityUnit string, expirationDate *time.Time, notes *string) storage.MaterialRead {
+	uid, _ := uuid.NewV4()
+	isExpense := false
+
+	return storage.MaterialRead{
+		UID:          uid,
+		Name:         name,
+		PricePerUnit: storage.PricePerUnit{Amount: "12.00", CurrencyCode: domain.MoneyEUR},
+		Type:         materialType,
+		Quantity: storage.MaterialQuantity{
+			Value: 20,
+			Unit:  domain.GetMaterialQuantityUnit(materialType.Code(), quantityUnit),
+		},
+		ExpirationDate: expirationDate,
+		Notes:          notes,
+		IsExpense:      &isExpense,
+		CreatedDate:    time.Date(2018, time.March, 10, 8, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestMaterialReadSqliteRoundTrip(t *testing.T) {
+	// Given
+	db := openMaterialReadDB(t)
+	defer db.Close()
+
+	repo := repoSqlite.NewMaterialReadRepositorySqlite(db)
+	q := NewMaterialReadQuerySqlite(db)
+
+	mtp, _ := domain.CreateMaterialTypePlantWithPackaging(domain.PlantTypeVegetable, string(domain.PlantPackagingSeedPackets))
+	notes := "Keep in a cool place"
+	producedBy := domain.ProducedBySupplier
+	plant := newMaterialRead("Chili Seedling", mtp, domain.MaterialUnitPackets, nil, &notes)
+	plant.ProducedBy = &producedBy
+
+	// When
+	err := <-repo.Save(&plant)
+	byID := <-q.FindByID(plant.UID)
+	byType := <-q.FindByType(domain.MaterialTypePlantCode)
+	all := <-q.FindAll(domain.MaterialTypePlantCode, "", false, 0, 0)
+
+	missingUID, _ := uuid.NewV4()
+	missing := <-q.FindByID(missingUID)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, byID.Error)
+	assert.Equal(t, plant, byID.Result)
+	assert.Nil(t, byType.Error)
+	assert.Equal(t, []storage.MaterialRead{plant}, byType.Result)
+	assert.Nil(t, all.Error)
+	assert.Equal(t, []storage.MaterialRead{plant}, all.Result)
+	assert.Nil(t, missing.Error)
+	assert.Equal(t, storage.MaterialRead{}, missing.Result)
+}
+
+func TestMaterialReadSqliteQueries(t *testing.T) {
+	// Given
+	db := openMaterialReadDB(t)
+	defer db.Close()
+
+	repo := repoSqlite.NewMaterialReadRepositorySqlite(db)
+	q := NewMaterialReadQuerySqlite(db)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	soon := now.AddDate(0, 0, 3)
+	later := now.AddDate(0, 0, 10)
+	farFuture := now.AddDate(1, 0, 0)
+	notes := "Bought from the Saturday market"
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	bayam := newMaterialRead("Bayam Lu Hsieh", mts, domain.MaterialUnitPackets, &later, &notes)
+	kangkung := newMaterialRead("Kangkung", mts, domain.MaterialUnitPackets, &soon, nil)
+	tomato := newMaterialRead("Tomato Cherry", mts, domain.MaterialUnitPackets, &farFuture, nil)
+	sawi := newMaterialRead("Sawi", mts, domain.MaterialUnitPackets, nil, nil)
+
+	for _, v := range []storage.MaterialRead{bayam, kangkung, tomato, sawi} {
+		materialRead := v
+		assert.Nil(t, <-repo.Save(&materialRead))
+	}
+
+	// When
+	seeds := <-q.FindByType(domain.MaterialTypeSeedCode)
+	agrochemicals := <-q.FindByType(domain.MaterialTypeAgrochemicalCode)
+	expiring := <-q.FindExpiringBefore(now.AddDate(0, 1, 0))
+	firstPage := <-q.FindAllPaged(false, 0, 3)
+	lastPage := <-q.FindAllPaged(false, 3, 3)
+	byName := <-q.Search("kANG")
+	byNotes := <-q.Search("saturday")
+	noMatch := <-q.Search("100%")
+
+	// Then
+	assert.Len(t, seeds.Result, 4)
+	assert.Equal(t, []storage.MaterialRead{}, agrochemicals.Result)
+	assert.Equal(t, []storage.MaterialRead{kangkung, bayam}, expiring.Result)
+	assert.Equal(t, query.MaterialPageQueryResult{Materials: []storage.MaterialRead{bayam, kangkung, sawi}, Total: 4}, firstPage.Result)
+	assert.Equal(t, query.MaterialPageQueryResult{Materials: []storage.MaterialRead{tomato}, Total: 4}, lastPage.Result)
+	assert.Equal(t, []storage.MaterialRead{kangkung}, byName.Result)
+	assert.Equal(t, []storage.MaterialRead{bayam}, byNotes.Result)
+	assert.Equal(t, []storage.MaterialRead{}, noMatch.Result)
+}
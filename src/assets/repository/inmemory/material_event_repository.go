@@ -1,6 +1,8 @@
 package inmemory
 
 import (
+	"time"
+
 	"github.com/Tanibox/tania-core/src/assets/repository"
 	"github.com/Tanibox/tania-core/src/assets/storage"
 	uuid "github.com/satori/go.uuid"
@@ -26,6 +28,7 @@ func (f *MaterialEventRepositoryInMemory) Save(uid uuid.UUID, latestVersion int,
 			f.Storage.MaterialEvents = append(f.Storage.MaterialEvents, storage.MaterialEvent{
 				MaterialUID: uid,
 				Version:     latestVersion,
+				CreatedDate: time.Now(),
 				Event:       v,
 			})
 		}
@@ -0,0 +1,320 @@
+package inmemory
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/repository"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	uuid "github.com/satori/go.uuid"
+)
+
+type MaterialRepositoryInMemory struct {
+	Storage *storage.MaterialEventStorage
+
+	locks repository.MaterialLocker
+}
+
+func NewMaterialRepositoryInMemory(s *storage.MaterialEventStorage) repository.MaterialRepository {
+	return &MaterialRepositoryInMemory{Storage: s}
+}
+
+func (f *MaterialRepositoryInMemory) Save(material *domain.Material, expectedVersion int) <-chan error {
+	result := make(chan error)
+
+	go func() {
+		f.Storage.Lock.Lock()
+		defer f.Storage.Lock.Unlock()
+
+		result <- f.save(material, expectedVersion)
+		close(result)
+	}()
+
+	return result
+}
+
+// FindOrSave saves the material unless another material was already created
+// with the same ExternalID, in which case that material is returned instead.
+func (f *MaterialRepositoryInMemory) FindOrSave(material *domain.Material) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		f.Storage.Lock.Lock()
+		defer f.Storage.Lock.Unlock()
+
+		if material.ExternalID != nil {
+			uid, ok := f.findUIDByExternalID(*material.ExternalID)
+			if ok {
+				existing := repository.NewMaterialFromHistory(f.eventsOf(uid))
+				result <- repository.RepositoryResult{Result: existing}
+				close(result)
+				return
+			}
+		}
+
+		err := f.save(material, material.BaseVersion())
+		if err != nil {
+			result <- repository.RepositoryResult{Error: err}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: material}
+		close(result)
+	}()
+
+	return result
+}
+
+// save appends the uncommitted events. The caller must hold the storage lock.
+func (f *MaterialRepositoryInMemory) save(material *domain.Material, expectedVersion int) error {
+	err := f.appendEvents(material, expectedVersion)
+	if err != nil {
+		return err
+	}
+
+	f.markSaved(material, expectedVersion)
+
+	return nil
+}
+
+// appendEvents checks the stored version and appends the uncommitted events,
+// leaving them uncommitted on the material. The caller must hold the storage lock.
+func (f *MaterialRepositoryInMemory) appendEvents(material *domain.Material, expectedVersion int) error {
+	storedVersion := 0
+	for _, v := range f.Storage.MaterialEvents {
+		if v.MaterialUID == material.UID && v.Version > storedVersion {
+			storedVersion = v.Version
+		}
+	}
+
+	if storedVersion != expectedVersion {
+		return repository.ErrConcurrentModification
+	}
+
+	if expectedVersion == 0 && material.ExternalID != nil {
+		if _, ok := f.findUIDByExternalID(*material.ExternalID); ok {
+			return repository.ErrDuplicateExternalID
+		}
+	}
+
+	latestVersion := expectedVersion
+	for _, v := range material.UncommittedChanges {
+		latestVersion++
+		f.Storage.MaterialEvents = append(f.Storage.MaterialEvents, storage.MaterialEvent{
+			MaterialUID: material.UID,
+			Version:     latestVersion,
+			CreatedDate: time.Now(),
+			Event:       v,
+		})
+	}
+
+	return nil
+}
+
+// findUIDByExternalID finds the material created with externalID from the MaterialCreated events only.
+// The caller must hold the storage lock.
+func (f *MaterialRepositoryInMemory) findUIDByExternalID(externalID string) (uuid.UUID, bool) {
+	for _, v := range f.Storage.MaterialEvents {
+		e, ok := v.Event.(domain.MaterialCreated)
+		if ok && e.ExternalID != nil && *e.ExternalID == externalID {
+			return v.MaterialUID, true
+		}
+	}
+
+	return uuid.UUID{}, false
+}
+
+// eventsOf returns the stored events of a material. The caller must hold the storage lock.
+func (f *MaterialRepositoryInMemory) eventsOf(uid uuid.UUID) []storage.MaterialEvent {
+	events := []storage.MaterialEvent{}
+	for _, v := range f.Storage.MaterialEvents {
+		if v.MaterialUID == uid {
+			events = append(events, v)
+		}
+	}
+
+	return events
+}
+
+// markSaved marks the changes of a material appended from expectedVersion committed,
+// and snapshots it when it crossed a snapshot interval.
+func (f *MaterialRepositoryInMemory) markSaved(material *domain.Material, expectedVersion int) {
+	latestVersion := expectedVersion + len(material.UncommittedChanges)
+
+	material.MarkChangesCommitted()
+
+	if latestVersion/repository.MaterialSnapshotInterval > expectedVersion/repository.MaterialSnapshotInterval {
+		if f.Storage.MaterialSnapshots == nil {
+			f.Storage.MaterialSnapshots = make(map[uuid.UUID]domain.MaterialSnapshot)
+		}
+		f.Storage.MaterialSnapshots[material.UID] = material.ToSnapshot()
+	}
+}
+
+func (f *MaterialRepositoryInMemory) FindByID(uid uuid.UUID) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		f.Storage.Lock.RLock()
+		defer f.Storage.Lock.RUnlock()
+
+		events := f.eventsOf(uid)
+		if len(events) == 0 {
+			result <- repository.RepositoryResult{Error: repository.ErrMaterialNotFound}
+			close(result)
+			return
+		}
+
+		if snap, ok := f.Storage.MaterialSnapshots[uid]; ok {
+			result <- repository.RepositoryResult{Result: repository.NewMaterialFromSnapshot(snap, events)}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: repository.NewMaterialFromHistory(events)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositoryInMemory) FindAll() <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		f.Storage.Lock.RLock()
+		defer f.Storage.Lock.RUnlock()
+
+		result <- repository.RepositoryResult{Result: repository.NewMaterialsFromHistory(f.Storage.MaterialEvents)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositoryInMemory) FindBySupplier(supplierUID uuid.UUID) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		f.Storage.Lock.RLock()
+		defer f.Storage.Lock.RUnlock()
+
+		materials := repository.NewMaterialsFromHistory(f.Storage.MaterialEvents)
+
+		result <- repository.RepositoryResult{Result: repository.FilterMaterialsBySupplier(materials, supplierUID)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositoryInMemory) FindByTag(tag string) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		f.Storage.Lock.RLock()
+		defer f.Storage.Lock.RUnlock()
+
+		materials := repository.NewMaterialsFromHistory(f.Storage.MaterialEvents)
+
+		result <- repository.RepositoryResult{Result: repository.FilterMaterialsByTag(materials, tag)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositoryInMemory) FindCreatedBetween(start, end time.Time) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		f.Storage.Lock.RLock()
+		defer f.Storage.Lock.RUnlock()
+
+		materials := repository.NewMaterialsFromHistory(f.Storage.MaterialEvents)
+
+		result <- repository.RepositoryResult{Result: repository.FilterMaterialsCreatedBetween(materials, start, end)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositoryInMemory) FindByPriceRange(currency string, min, max float64) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		f.Storage.Lock.RLock()
+		defer f.Storage.Lock.RUnlock()
+
+		materials := repository.NewMaterialsFromHistory(f.Storage.MaterialEvents)
+
+		filtered, err := repository.FilterMaterialsByPriceRange(materials, currency, min, max)
+		if err != nil {
+			result <- repository.RepositoryResult{Error: err}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: filtered}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositoryInMemory) FindIncomplete() <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		f.Storage.Lock.RLock()
+		defer f.Storage.Lock.RUnlock()
+
+		materials := repository.NewMaterialsFromHistory(f.Storage.MaterialEvents)
+
+		result <- repository.RepositoryResult{Result: repository.FilterIncompleteMaterials(materials)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositoryInMemory) FindBySKU(supplierID uuid.UUID, sku string) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		f.Storage.Lock.RLock()
+		defer f.Storage.Lock.RUnlock()
+
+		materials := repository.NewMaterialsFromHistory(f.Storage.MaterialEvents)
+
+		result <- repository.RepositoryResult{Result: repository.FilterMaterialsBySKU(materials, supplierID, sku)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositoryInMemory) WithMaterial(uid uuid.UUID, fn func(*domain.Material) error) error {
+	unlock := f.locks.Lock(uid)
+	defer unlock()
+
+	result := <-f.FindByID(uid)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	material, ok := result.Result.(*domain.Material)
+	if !ok {
+		return errors.New("Internal server error. Error type assertion")
+	}
+
+	err := fn(material)
+	if err != nil {
+		return err
+	}
+
+	return <-f.Save(material, material.BaseVersion())
+}
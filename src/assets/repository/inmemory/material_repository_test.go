@@ -0,0 +1,428 @@
+package inmemory
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/repository"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaterialInMemorySaveAndFind(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	expDate := time.Now().AddDate(0, 6, 0)
+	notes := "Keep in a cool place"
+	producedBy := domain.ProducedBySupplier
+
+	materials := []*domain.Material{}
+	for i := 0; i < 10; i++ {
+		m, err := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, &expDate, &notes, &producedBy, nil, nil, nil)
+		assert.Nil(t, err)
+
+		m.ChangeName("Bayam Hijau", time.Now(), uuid.Nil)
+
+		materials = append(materials, m)
+	}
+
+	// When
+	var wg sync.WaitGroup
+	errs := make([]error, len(materials))
+	for i, m := range materials {
+		wg.Add(1)
+		go func(i int, m *domain.Material) {
+			defer wg.Done()
+			errs[i] = <-repo.Save(m, m.BaseVersion())
+		}(i, m)
+	}
+	wg.Wait()
+
+	// Then
+	for i, m := range materials {
+		assert.Nil(t, errs[i])
+		assert.Empty(t, m.UncommittedChanges)
+
+		result := <-repo.FindByID(m.UID)
+		assert.Nil(t, result.Error)
+
+		found, ok := result.Result.(*domain.Material)
+		assert.True(t, ok)
+		assert.Equal(t, m.UID, found.UID)
+		assert.Equal(t, "Bayam Hijau", found.Name)
+		assert.Equal(t, m.PricePerUnit, found.PricePerUnit)
+		assert.Equal(t, m.Type, found.Type)
+		assert.Equal(t, m.Quantity, found.Quantity)
+		assert.Equal(t, m.ExpirationDate, found.ExpirationDate)
+		assert.Equal(t, m.Notes, found.Notes)
+		assert.Equal(t, m.ProducedBy, found.ProducedBy)
+		assert.Equal(t, m.IsExpense, found.IsExpense)
+		assert.Equal(t, m.CreatedDate, found.CreatedDate)
+		assert.Equal(t, m.Version, found.Version)
+	}
+
+	result := <-repo.FindAll()
+	assert.Nil(t, result.Error)
+	assert.Len(t, result.Result, len(materials))
+}
+
+func TestMaterialInMemorySaveConcurrentModification(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	err := <-repo.Save(material, material.BaseVersion())
+	assert.Nil(t, err)
+
+	result1 := <-repo.FindByID(material.UID)
+	result2 := <-repo.FindByID(material.UID)
+	operator1 := result1.Result.(*domain.Material)
+	operator2 := result2.Result.(*domain.Material)
+
+	// When
+	operator1.ChangeName("Bayam Merah", time.Now(), uuid.Nil)
+	operator2.ChangeName("Bayam Hijau", time.Now(), uuid.Nil)
+
+	err1 := <-repo.Save(operator1, operator1.BaseVersion())
+	err2 := <-repo.Save(operator2, operator2.BaseVersion())
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, repository.ErrConcurrentModification, err2)
+	assert.Len(t, operator2.UncommittedChanges, 1)
+
+	result := <-repo.FindByID(material.UID)
+	assert.Equal(t, "Bayam Merah", result.Result.(*domain.Material).Name)
+	assert.Equal(t, 2, result.Result.(*domain.Material).Version)
+}
+
+func TestMaterialInMemoryFindByIDNotFound(t *testing.T) {
+	// Given
+	repo := NewMaterialRepositoryInMemory(storage.CreateMaterialEventStorage())
+	material := domain.Material{}
+
+	// When
+	result := <-repo.FindByID(material.UID)
+
+	// Then
+	assert.Equal(t, errors.New("material not found"), result.Error)
+	assert.True(t, errors.Is(result.Error, repository.ErrMaterialNotFound))
+}
+
+func TestMaterialInMemoryFindBySupplier(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	supplier1, _ := uuid.NewV4()
+	supplier2, _ := uuid.NewV4()
+
+	mta, _ := domain.CreateMaterialTypeAgrochemical(domain.ChemicalTypeFertilizer)
+	npk, _ := domain.CreateMaterial("NPK", "5", domain.MoneyEUR, mta, 10, domain.MaterialUnitBags, nil, nil, nil, nil, &supplier1, nil)
+	urea, _ := domain.CreateMaterial("Urea", "4", domain.MoneyEUR, mta, 10, domain.MaterialUnitBags, nil, nil, nil, nil, &supplier1, nil)
+	compost, _ := domain.CreateMaterial("Compost", "2", domain.MoneyEUR, mta, 10, domain.MaterialUnitBags, nil, nil, nil, nil, nil, nil)
+
+	urea.ChangeSupplier(&supplier2, time.Now(), uuid.Nil)
+
+	for _, m := range []*domain.Material{npk, urea, compost} {
+		<-repo.Save(m, m.BaseVersion())
+	}
+
+	// When
+	result1 := <-repo.FindBySupplier(supplier1)
+	result2 := <-repo.FindBySupplier(supplier2)
+
+	// Then
+	assert.Nil(t, result1.Error)
+	materials1 := result1.Result.([]domain.Material)
+	assert.Len(t, materials1, 1)
+	assert.Equal(t, npk.UID, materials1[0].UID)
+
+	materials2 := result2.Result.([]domain.Material)
+	assert.Len(t, materials2, 1)
+	assert.Equal(t, urea.UID, materials2[0].UID)
+	assert.Equal(t, supplier2, *materials2[0].SupplierID)
+}
+
+func TestMaterialInMemoryFindOrSave(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	externalID := "import-2026-0042"
+
+	first, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, &externalID)
+	retry, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, &externalID)
+	other, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	duplicate, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, &externalID)
+
+	// When
+	result1 := <-repo.FindOrSave(first)
+	result2 := <-repo.FindOrSave(retry)
+	result3 := <-repo.FindOrSave(other)
+	err := <-repo.Save(duplicate, duplicate.BaseVersion())
+
+	// Then
+	assert.Nil(t, result1.Error)
+	assert.Nil(t, result2.Error)
+	assert.Nil(t, result3.Error)
+	assert.Equal(t, first.UID, result1.Result.(*domain.Material).UID)
+	assert.Equal(t, first.UID, result2.Result.(*domain.Material).UID)
+	assert.Equal(t, other.UID, result3.Result.(*domain.Material).UID)
+	assert.Equal(t, repository.ErrDuplicateExternalID, err)
+
+	all := <-repo.FindAll()
+	assert.Len(t, all.Result, 2)
+}
+
+func TestMaterialInMemorySnapshot(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitKilogram, nil, nil, nil, nil, nil, nil)
+	for i := 0; i < repository.MaterialSnapshotInterval+2; i++ {
+		material.Reserve(0.5)
+	}
+
+	// When
+	err := <-repo.Save(material, material.BaseVersion())
+
+	material.ReleaseReservation(1)
+	err2 := <-repo.Save(material, material.BaseVersion())
+
+	result := <-repo.FindByID(material.UID)
+
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, err2)
+	assert.Nil(t, result.Error)
+
+	snap, ok := materialEventStorage.MaterialSnapshots[material.UID]
+	assert.True(t, ok)
+	assert.Equal(t, repository.MaterialSnapshotInterval+3, snap.Version)
+
+	found := result.Result.(*domain.Material)
+	assert.Equal(t, repository.NewMaterialFromHistory(materialEventStorage.MaterialEvents), found)
+	assert.Equal(t, material.Version, found.Version)
+	assert.Equal(t, material.Reserved, found.Reserved)
+}
+
+func TestMaterialInMemoryWithMaterialConcurrentConsume(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 100, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	err := <-repo.Save(material, material.BaseVersion())
+	assert.Nil(t, err)
+
+	// When
+	var wg sync.WaitGroup
+	errs := make([]error, 60)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.WithMaterial(material.UID, func(m *domain.Material) error {
+				return m.ConsumeQuantity(1)
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	// Then
+	for _, err := range errs {
+		assert.Nil(t, err)
+	}
+
+	result := <-repo.FindByID(material.UID)
+	assert.Nil(t, result.Error)
+	assert.Equal(t, float32(40), result.Result.(*domain.Material).Quantity.Value)
+}
+
+func TestMaterialInMemoryWithMaterialError(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 1, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	<-repo.Save(material, material.BaseVersion())
+
+	// When
+	err := repo.WithMaterial(material.UID, func(m *domain.Material) error {
+		return m.ConsumeQuantity(5)
+	})
+	unknownUID, _ := uuid.NewV4()
+	errNotFound := repo.WithMaterial(unknownUID, func(m *domain.Material) error {
+		return nil
+	})
+
+	// Then
+	assert.NotNil(t, err)
+	assert.NotNil(t, errNotFound)
+	assert.Len(t, materialEventStorage.MaterialEvents, 1)
+}
+
+func TestMaterialInMemoryFindByTag(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	bayam, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	bayam.AddTag("organic")
+	bayam.AddTag("imported")
+	kangkung, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	kangkung.AddTag("bulk")
+
+	<-repo.Save(bayam, bayam.BaseVersion())
+	<-repo.Save(kangkung, kangkung.BaseVersion())
+
+	// When
+	result1 := <-repo.FindByTag("Organic")
+	result2 := <-repo.FindByTag("seasonal")
+
+	// Then
+	assert.Nil(t, result1.Error)
+	materials1 := result1.Result.([]domain.Material)
+	assert.Len(t, materials1, 1)
+	assert.Equal(t, bayam.UID, materials1[0].UID)
+
+	assert.Nil(t, result2.Error)
+	assert.Empty(t, result2.Result)
+}
+
+func TestMaterialInMemoryFindCreatedBetween(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	start := time.Now()
+	bayam, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	kangkung, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	end := time.Now().Add(time.Second)
+
+	<-repo.Save(bayam, bayam.BaseVersion())
+	<-repo.Save(kangkung, kangkung.BaseVersion())
+
+	// When
+	result1 := <-repo.FindCreatedBetween(start, end)
+	result2 := <-repo.FindCreatedBetween(start.AddDate(0, 0, -7), start)
+	result3 := <-repo.FindCreatedBetween(bayam.CreatedDate, bayam.CreatedDate.Add(time.Nanosecond))
+
+	// Then
+	assert.Nil(t, result1.Error)
+	assert.Len(t, result1.Result, 2)
+	assert.Nil(t, result2.Error)
+	assert.Empty(t, result2.Result)
+	assert.Nil(t, result3.Error)
+	assert.Equal(t, bayam.UID, result3.Result.([]domain.Material)[0].UID)
+}
+
+func TestMaterialInMemoryFindByPriceRange(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	inside, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	lower, _ := domain.CreateMaterial("Kangkung", "5", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	upper, _ := domain.CreateMaterial("Sawi", "20", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	outside, _ := domain.CreateMaterial("Selada", "20.01", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	otherCurrency, _ := domain.CreateMaterial("Tomat", "10", domain.MoneyIDR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	for _, v := range []*domain.Material{inside, lower, upper, outside, otherCurrency} {
+		<-repo.Save(v, v.BaseVersion())
+	}
+
+	// When
+	result := <-repo.FindByPriceRange("eur", 5, 20)
+
+	// Then
+	assert.Nil(t, result.Error)
+
+	uids := []uuid.UUID{}
+	for _, v := range result.Result.([]domain.Material) {
+		uids = append(uids, v.UID)
+	}
+
+	assert.ElementsMatch(t, []uuid.UUID{inside.UID, lower.UID, upper.UID}, uids)
+
+	// When
+	unknownCurrency := <-repo.FindByPriceRange("XYZ", 5, 20)
+	inverted := <-repo.FindByPriceRange(domain.MoneyEUR, 20, 5)
+
+	// Then
+	assert.NotNil(t, unknownCurrency.Error)
+	assert.Equal(t, errors.New("min price cannot be greater than max price"), inverted.Error)
+}
+
+func TestMaterialInMemoryFindIncomplete(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	expDate := time.Now().AddDate(0, 6, 0)
+	incomplete, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	complete, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, &expDate, nil, nil, nil, nil, nil)
+	tray, _ := domain.CreateMaterial("Tray", "2", domain.MoneyEUR, domain.MaterialTypeLabelAndCropSupport{}, 10, domain.MaterialUnitPieces, nil, nil, nil, nil, nil, nil)
+
+	<-repo.Save(incomplete, incomplete.BaseVersion())
+	<-repo.Save(complete, complete.BaseVersion())
+	<-repo.Save(tray, tray.BaseVersion())
+
+	// When
+	result := <-repo.FindIncomplete()
+
+	// Then
+	assert.Nil(t, result.Error)
+
+	materials := result.Result.([]domain.Material)
+	assert.Len(t, materials, 1)
+	assert.Equal(t, incomplete.UID, materials[0].UID)
+	assert.Equal(t, []string{"expiration_date"}, materials[0].MissingData())
+}
+
+func TestMaterialInMemoryFindBySKU(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	supplierA, _ := uuid.NewV4()
+	supplierB, _ := uuid.NewV4()
+	bayam, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	bayam.SetSupplierSKU(supplierA, "TS-0042")
+	kangkung, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	kangkung.SetSupplierSKU(supplierB, "TS-0042")
+
+	<-repo.Save(bayam, bayam.BaseVersion())
+	<-repo.Save(kangkung, kangkung.BaseVersion())
+
+	// When
+	result1 := <-repo.FindBySKU(supplierA, "TS-0042")
+	result2 := <-repo.FindBySKU(supplierA, "TS-0043")
+
+	// Then
+	assert.Nil(t, result1.Error)
+	assert.Len(t, result1.Result, 1)
+	assert.Equal(t, bayam.UID, result1.Result.([]domain.Material)[0].UID)
+	assert.Nil(t, result2.Error)
+	assert.Empty(t, result2.Result)
+}
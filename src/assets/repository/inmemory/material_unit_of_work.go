@@ -0,0 +1,56 @@
+package inmemory
+
+import (
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/repository"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+)
+
+type MaterialUnitOfWorkInMemory struct {
+	Storage *storage.MaterialEventStorage
+
+	materials []*domain.Material
+}
+
+func NewMaterialUnitOfWorkInMemory(s *storage.MaterialEventStorage) repository.MaterialUnitOfWork {
+	return &MaterialUnitOfWorkInMemory{Storage: s}
+}
+
+func (u *MaterialUnitOfWorkInMemory) Register(material *domain.Material) {
+	for _, v := range u.materials {
+		if v == material {
+			return
+		}
+	}
+
+	u.materials = append(u.materials, material)
+}
+
+// Commit appends the events of every registered material while holding the storage lock,
+// and drops the appended events again when one of the materials fails its version check.
+func (u *MaterialUnitOfWorkInMemory) Commit() error {
+	u.Storage.Lock.Lock()
+	defer u.Storage.Lock.Unlock()
+
+	repo := &MaterialRepositoryInMemory{Storage: u.Storage}
+	stored := len(u.Storage.MaterialEvents)
+
+	expectedVersions := make([]int, len(u.materials))
+	for i, v := range u.materials {
+		expectedVersions[i] = v.BaseVersion()
+
+		err := repo.appendEvents(v, expectedVersions[i])
+		if err != nil {
+			u.Storage.MaterialEvents = u.Storage.MaterialEvents[:stored]
+			return err
+		}
+	}
+
+	for i, v := range u.materials {
+		repo.markSaved(v, expectedVersions[i])
+	}
+
+	u.materials = nil
+
+	return nil
+}
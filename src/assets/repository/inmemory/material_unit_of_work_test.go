@@ -0,0 +1,64 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/repository"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaterialUnitOfWorkCommit(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	uow := NewMaterialUnitOfWorkInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	bayam, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	kangkung, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	uow.Register(bayam)
+	uow.Register(kangkung)
+	uow.Register(bayam)
+	err := uow.Commit()
+
+	// Then
+	assert.Nil(t, err)
+	assert.Len(t, materialEventStorage.MaterialEvents, 2)
+	assert.Empty(t, bayam.UncommittedChanges)
+	assert.Empty(t, kangkung.UncommittedChanges)
+}
+
+func TestMaterialUnitOfWorkRollback(t *testing.T) {
+	// Given
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	repo := NewMaterialRepositoryInMemory(materialEventStorage)
+	uow := NewMaterialUnitOfWorkInMemory(materialEventStorage)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	bayam, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	kangkung, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	<-repo.Save(kangkung, kangkung.BaseVersion())
+
+	stale := kangkung.Clone()
+	kangkung.ConsumeQuantity(2)
+	<-repo.Save(kangkung, kangkung.BaseVersion())
+
+	stale.ConsumeQuantity(3)
+
+	// When
+	uow.Register(bayam)
+	uow.Register(stale)
+	err := uow.Commit()
+
+	// Then
+	assert.Equal(t, repository.ErrConcurrentModification, err)
+	assert.Len(t, materialEventStorage.MaterialEvents, 2)
+	assert.Len(t, bayam.UncommittedChanges, 1)
+	assert.Len(t, stale.UncommittedChanges, 1)
+
+	result := <-repo.FindByID(bayam.UID)
+	assert.NotNil(t, result.Error)
+}
@@ -2,13 +2,9 @@ package mysql
 
 import (
 	"database/sql"
-	"encoding/json"
 	"time"
 
-	"github.com/Tanibox/tania-core/src/assets/decoder"
-	"github.com/Tanibox/tania-core/src/assets/domain"
 	"github.com/Tanibox/tania-core/src/assets/repository"
-	"github.com/Tanibox/tania-core/src/helper/structhelper"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -24,50 +20,38 @@ func (f *MaterialEventRepositoryMysql) Save(uid uuid.UUID, latestVersion int, ev
 	result := make(chan error)
 
 	go func() {
-		for _, v := range events {
-			stmt, err := f.DB.Prepare(`INSERT INTO MATERIAL_EVENT (MATERIAL_UID, VERSION, CREATED_DATE, EVENT) VALUES (?, ?, ?, ?)`)
-			if err != nil {
-				result <- err
-			}
-
-			latestVersion++
-
-			var eTemp interface{}
-			switch val := v.(type) {
-			case domain.MaterialCreated:
-				val.Type = repository.MaterialEventTypeWrapper{
-					Type: val.Type.Code(),
-					Data: val.Type,
-				}
-
-				eTemp = val
-
-			case domain.MaterialTypeChanged:
-				val.MaterialType = repository.MaterialEventTypeWrapper{
-					Type: val.MaterialType.Code(),
-					Data: val.MaterialType,
-				}
-
-				eTemp = val
-
-			default:
-				eTemp = val
-			}
+		result <- f.save(uid, latestVersion, events)
+		close(result)
+	}()
 
-			e, err := json.Marshal(decoder.EventWrapper{
-				EventName: structhelper.GetName(eTemp),
-				EventData: eTemp,
-			})
+	return result
+}
 
-			_, err = stmt.Exec(uid.Bytes(), latestVersion, time.Now(), e)
-			if err != nil {
-				result <- err
-			}
+// save inserts the events after latestVersion. A version that was already stored,
+// because someone else saved the material in between, fails with ErrConcurrentModification.
+func (f *MaterialEventRepositoryMysql) save(uid uuid.UUID, latestVersion int, events []interface{}) error {
+	stmt, err := f.DB.Prepare(`INSERT INTO MATERIAL_EVENT (MATERIAL_UID, VERSION, CREATED_DATE, EVENT) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, v := range events {
+		latestVersion++
+
+		e, err := repository.MarshalMaterialEvent(v)
+		if err != nil {
+			return err
 		}
 
-		result <- nil
-		close(result)
-	}()
+		_, err = stmt.Exec(uid.Bytes(), latestVersion, time.Now(), e)
+		if isUniqueViolation(err) {
+			return repository.ErrConcurrentModification
+		}
+		if err != nil {
+			return err
+		}
+	}
 
-	return result
+	return nil
 }
@@ -32,7 +32,11 @@ func (f *MaterialReadRepositoryMysql) Save(materialRead *storage.MaterialRead) <
 		case domain.MaterialTypeSeed:
 			typeData = t.PlantType.Code
 		case domain.MaterialTypePlant:
+			// The packaging follows the plant type, e.g. "VEGETABLE:SEED_PACKETS"
 			typeData = t.PlantType.Code
+			if t.Packaging != domain.PlantPackagingUnspecified {
+				typeData += ":" + string(t.Packaging)
+			}
 		case domain.MaterialTypeAgrochemical:
 			typeData = t.ChemicalType.Code
 		case domain.MaterialTypeSeedingContainer:
@@ -48,7 +52,7 @@ func (f *MaterialReadRepositoryMysql) Save(materialRead *storage.MaterialRead) <
 			_, err = f.DB.Exec(`UPDATE MATERIAL_READ SET
 				NAME = ?, PRICE_PER_UNIT = ?, CURRENCY_CODE = ?, TYPE = ?, TYPE_DATA = ?,
 				QUANTITY = ?, QUANTITY_UNIT = ?, EXPIRATION_DATE = ?, NOTES = ?,
-				PRODUCED_BY = ?, CREATED_DATE = ?
+				PRODUCED_BY = ?, IS_EXPENSE = ?, CREATED_DATE = ?, IS_ARCHIVED = ?
 				WHERE UID = ?`,
 				materialRead.Name,
 				materialRead.PricePerUnit.Amount,
@@ -60,7 +64,9 @@ func (f *MaterialReadRepositoryMysql) Save(materialRead *storage.MaterialRead) <
 				expirationDate,
 				materialRead.Notes,
 				materialRead.ProducedBy,
+				materialRead.IsExpense,
 				materialRead.CreatedDate,
+				materialRead.Archived,
 				materialRead.UID.Bytes())
 
 			if err != nil {
@@ -70,8 +76,8 @@ func (f *MaterialReadRepositoryMysql) Save(materialRead *storage.MaterialRead) <
 		} else {
 			_, err = f.DB.Exec(`INSERT INTO MATERIAL_READ
 				(UID, NAME, PRICE_PER_UNIT, CURRENCY_CODE, TYPE, TYPE_DATA, QUANTITY,
-				QUANTITY_UNIT, EXPIRATION_DATE, NOTES, PRODUCED_BY, CREATED_DATE)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				QUANTITY_UNIT, EXPIRATION_DATE, NOTES, PRODUCED_BY, IS_EXPENSE, CREATED_DATE, IS_ARCHIVED)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 				materialRead.UID.Bytes(),
 				materialRead.Name,
 				materialRead.PricePerUnit.Amount,
@@ -83,7 +89,9 @@ func (f *MaterialReadRepositoryMysql) Save(materialRead *storage.MaterialRead) <
 				expirationDate,
 				materialRead.Notes,
 				materialRead.ProducedBy,
-				materialRead.CreatedDate)
+				materialRead.IsExpense,
+				materialRead.CreatedDate,
+				materialRead.Archived)
 
 			if err != nil {
 				result <- err
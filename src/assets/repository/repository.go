@@ -1,8 +1,16 @@
 package repository
 
 import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/decoder"
 	"github.com/Tanibox/tania-core/src/assets/domain"
 	"github.com/Tanibox/tania-core/src/assets/storage"
+	"github.com/Tanibox/tania-core/src/helper/structhelper"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -69,12 +77,237 @@ type MaterialEventRepository interface {
 }
 
 func NewMaterialFromHistory(events []storage.MaterialEvent) *domain.Material {
-	state := &domain.Material{}
+	e := make([]interface{}, len(events))
+	for i, v := range events {
+		e[i] = materialEventData(v)
+	}
+	return domain.NewMaterialFromHistory(e)
+}
+
+// materialEventData returns the domain event of a stored event. Price changes
+// stored before they carried a ChangedAt get the date the event was stored,
+// so the price history still knows when they took effect.
+func materialEventData(v storage.MaterialEvent) interface{} {
+	if e, ok := v.Event.(domain.MaterialPriceChanged); ok && e.ChangedAt.IsZero() {
+		e.ChangedAt = v.CreatedDate
+		return e
+	}
+
+	return v.Event
+}
+
+// MaterialSnapshotInterval is how many events are saved between two snapshots of a material.
+const MaterialSnapshotInterval = 20
+
+// NewMaterialFromSnapshot restores the material from its snapshot
+// and replays only the events stored after it.
+func NewMaterialFromSnapshot(snap domain.MaterialSnapshot, events []storage.MaterialEvent) *domain.Material {
+	e := []interface{}{}
 	for _, v := range events {
-		state.Transition(v.Event)
-		state.Version++
+		if v.Version > snap.Version {
+			e = append(e, materialEventData(v))
+		}
+	}
+	return domain.NewMaterialFromSnapshot(snap, e)
+}
+
+// NewMaterialsFromHistory replays the events of several materials.
+// The materials are returned in the order their first event appears.
+func NewMaterialsFromHistory(events []storage.MaterialEvent) []domain.Material {
+	uids := []uuid.UUID{}
+	grouped := make(map[uuid.UUID][]storage.MaterialEvent)
+
+	for _, v := range events {
+		if _, ok := grouped[v.MaterialUID]; !ok {
+			uids = append(uids, v.MaterialUID)
+		}
+
+		grouped[v.MaterialUID] = append(grouped[v.MaterialUID], v)
+	}
+
+	materials := []domain.Material{}
+	for _, uid := range uids {
+		materials = append(materials, *NewMaterialFromHistory(grouped[uid]))
+	}
+
+	return materials
+}
+
+// FilterMaterialsBySupplier keeps the materials bought from the given supplier.
+func FilterMaterialsBySupplier(materials []domain.Material, supplierUID uuid.UUID) []domain.Material {
+	filtered := []domain.Material{}
+	for _, v := range materials {
+		if v.SupplierID != nil && *v.SupplierID == supplierUID {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered
+}
+
+// FilterMaterialsByTag keeps the materials tagged with tag.
+func FilterMaterialsByTag(materials []domain.Material, tag string) []domain.Material {
+	filtered := []domain.Material{}
+	for _, v := range materials {
+		if v.HasTag(tag) {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered
+}
+
+// FilterMaterialsCreatedBetween keeps the materials created from start, inclusive, to end, exclusive.
+func FilterMaterialsCreatedBetween(materials []domain.Material, start, end time.Time) []domain.Material {
+	filtered := []domain.Material{}
+	for _, v := range materials {
+		if !v.CreatedDate.Before(start) && v.CreatedDate.Before(end) {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered
+}
+
+// FilterMaterialsByPriceRange keeps the materials priced in currency from min to max per unit,
+// both inclusive. Materials priced in another currency are left out.
+// An unknown currency or a min above max is an error.
+func FilterMaterialsByPriceRange(materials []domain.Material, currency string, min, max float64) ([]domain.Material, error) {
+	cc, err := domain.GetCurrencyCode(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if min > max {
+		return nil, errors.New("min price cannot be greater than max price")
+	}
+
+	filtered := []domain.Material{}
+	for _, v := range materials {
+		if v.PricePerUnit.CurrencyCode != cc {
+			continue
+		}
+
+		amount, err := v.PricePerUnit.AmountFloat()
+		if err != nil {
+			continue
+		}
+
+		if amount >= min && amount <= max {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered, nil
+}
+
+// FilterIncompleteMaterials keeps the materials that fail domain.MaterialCompletenessRules.
+func FilterIncompleteMaterials(materials []domain.Material) []domain.Material {
+	filtered := []domain.Material{}
+	for _, v := range materials {
+		if !v.IsComplete() {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered
+}
+
+// FilterMaterialsBySKU keeps the materials the supplier sells under sku.
+func FilterMaterialsBySKU(materials []domain.Material, supplierID uuid.UUID, sku string) []domain.Material {
+	filtered := []domain.Material{}
+	for _, v := range materials {
+		if current, ok := v.SupplierSKUs[supplierID]; ok && current == strings.TrimSpace(sku) {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered
+}
+
+// ErrMaterialNotFound is returned by FindByID when no material has the ID.
+var ErrMaterialNotFound = errors.New("material not found")
+
+// ErrDuplicateExternalID is returned by Save when a new material has the ExternalID
+// of a material that was already saved.
+var ErrDuplicateExternalID = errors.New("material external ID already exists")
+
+// ErrConcurrentModification is returned when the stored version of an aggregate
+// is not the version the caller loaded, meaning someone else saved it in between.
+var ErrConcurrentModification = errors.New("aggregate was modified concurrently")
+
+// MaterialRepository stores and loads the Material aggregate through its events.
+// Save persists the uncommitted changes of the material and then marks them committed.
+// It fails with ErrConcurrentModification when the stored version is not expectedVersion,
+// which is usually material.BaseVersion().
+// External IDs are unique: saving a new material with the ExternalID of another fails
+// with ErrDuplicateExternalID. FindOrSave makes creation idempotent: when a material
+// with the same ExternalID was already saved, that material is the result and nothing is saved.
+// WithMaterial loads the material while holding its lock, applies fn and saves the result,
+// so concurrent mutations of the same material are applied one after another.
+// Nothing is saved when fn returns an error.
+type MaterialRepository interface {
+	Save(material *domain.Material, expectedVersion int) <-chan error
+	FindOrSave(material *domain.Material) <-chan RepositoryResult
+	FindByID(uid uuid.UUID) <-chan RepositoryResult
+	FindAll() <-chan RepositoryResult
+	FindBySupplier(supplierUID uuid.UUID) <-chan RepositoryResult
+	FindByTag(tag string) <-chan RepositoryResult
+	FindCreatedBetween(start, end time.Time) <-chan RepositoryResult
+	FindByPriceRange(currency string, min, max float64) <-chan RepositoryResult
+	FindIncomplete() <-chan RepositoryResult
+	FindBySKU(supplierID uuid.UUID, sku string) <-chan RepositoryResult
+	WithMaterial(uid uuid.UUID, fn func(*domain.Material) error) error
+}
+
+// MaterialUnitOfWork saves several materials at once, e.g. both sides of a merge.
+// Register adds a material whose uncommitted changes are to be saved.
+// Commit saves the changes of every registered material, each checked against its BaseVersion,
+// or none of them when one fails. The changes are marked committed only when all are saved.
+type MaterialUnitOfWork interface {
+	Register(material *domain.Material)
+	Commit() error
+}
+
+// MaterialLocker hands out one mutex per material ID. Its zero value is ready to use.
+// A mutex is only kept while the material is locked or waited for,
+// so materials that were locked once don't pile up.
+type MaterialLocker struct {
+	mu    sync.Mutex
+	locks map[uuid.UUID]*materialLock
+}
+
+// materialLock is the mutex of a material and how many callers hold or wait for it.
+type materialLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock locks the material with the given ID and returns the function that unlocks it.
+func (l *MaterialLocker) Lock(uid uuid.UUID) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[uuid.UUID]*materialLock)
+	}
+	lock, ok := l.locks[uid]
+	if !ok {
+		lock = &materialLock{}
+		l.locks[uid] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, uid)
+		}
+		l.mu.Unlock()
 	}
-	return state
 }
 
 type MaterialEventTypeWrapper struct {
@@ -86,6 +319,117 @@ func (w MaterialEventTypeWrapper) Code() string {
 	return w.Type
 }
 
+// MarshalMaterialEvent encodes a material event for the event store, in a decoder.EventWrapper
+// tagged with the event's name. The material type inside an event is wrapped with
+// its code, so UnmarshalMaterialEvent can pick the concrete type back.
+func MarshalMaterialEvent(event interface{}) ([]byte, error) {
+	var eTemp interface{}
+	switch val := event.(type) {
+	case domain.MaterialCreated:
+		val.Type = MaterialEventTypeWrapper{
+			Type: val.Type.Code(),
+			Data: val.Type,
+		}
+
+		eTemp = val
+
+	case domain.MaterialTypeChanged:
+		val.MaterialType = MaterialEventTypeWrapper{
+			Type: val.MaterialType.Code(),
+			Data: val.MaterialType,
+		}
+
+		eTemp = val
+
+	default:
+		eTemp = val
+	}
+
+	return json.Marshal(decoder.EventWrapper{
+		EventName: structhelper.GetName(eTemp),
+		EventData: eTemp,
+	})
+}
+
+// UnmarshalMaterialEvent decodes an event encoded by MarshalMaterialEvent
+// back to its concrete event struct.
+func UnmarshalMaterialEvent(b []byte) (interface{}, error) {
+	wrapper := decoder.MaterialEventWrapper{}
+
+	err := json.Unmarshal(b, &wrapper)
+	if err != nil {
+		return nil, err
+	}
+
+	if wrapper.EventData == nil {
+		return nil, errors.New("unknown material event")
+	}
+
+	return wrapper.EventData, nil
+}
+
+// materialSnapshotRecord is how a material snapshot is encoded for the snapshot store.
+// The material type is taken out of the state and wrapped with its code,
+// the same way MarshalMaterialEvent does it for events.
+type materialSnapshotRecord struct {
+	State        domain.Material
+	Type         MaterialEventTypeWrapper
+	Version      int
+	History      []domain.ChangeRecord
+	PriceHistory []domain.PricedAt
+}
+
+// MarshalMaterialSnapshot encodes a material snapshot for the snapshot store.
+func MarshalMaterialSnapshot(snap domain.MaterialSnapshot) ([]byte, error) {
+	record := materialSnapshotRecord{
+		State:        snap.State,
+		Version:      snap.Version,
+		History:      snap.History,
+		PriceHistory: snap.PriceHistory,
+	}
+
+	if snap.State.Type != nil {
+		record.Type = MaterialEventTypeWrapper{
+			Type: snap.State.Type.Code(),
+			Data: snap.State.Type,
+		}
+	}
+	record.State.Type = nil
+
+	return json.Marshal(record)
+}
+
+// UnmarshalMaterialSnapshot decodes a snapshot encoded by MarshalMaterialSnapshot.
+func UnmarshalMaterialSnapshot(b []byte) (domain.MaterialSnapshot, error) {
+	record := struct {
+		materialSnapshotRecord
+		Type map[string]interface{}
+	}{}
+
+	err := json.Unmarshal(b, &record)
+	if err != nil {
+		return domain.MaterialSnapshot{}, err
+	}
+
+	materialType := struct {
+		Type domain.MaterialType `json:"type"`
+	}{}
+
+	_, err = decoder.Decode(decoder.MaterialTypeHook(), &map[string]interface{}{"type": record.Type}, &materialType)
+	if err != nil {
+		return domain.MaterialSnapshot{}, err
+	}
+
+	record.State.Type = materialType.Type
+
+	return domain.MaterialSnapshot{
+		State:        record.State,
+		Version:      record.Version,
+		History:      record.History,
+		PriceHistory: record.PriceHistory,
+	}, nil
+}
+
 type MaterialReadRepository interface {
 	Save(materialRead *storage.MaterialRead) <-chan error
 }
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaterialEventRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	uid, _ := uuid.NewV4()
+	otherUID, _ := uuid.NewV4()
+	date := time.Date(2018, time.March, 10, 8, 30, 0, 0, time.UTC)
+	notes := "Keep it dry"
+	producedBy := "Tani Supplier"
+	isExpense := true
+	threshold := float32(5)
+	price := domain.PricePerUnit{Amount: "12.50", CurrencyCode: domain.MoneyEUR}
+	quantity := domain.MaterialQuantity{Value: 20, Unit: domain.MaterialQuantityUnit{Code: domain.MaterialUnitPackets, Label: "Packets"}}
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	mta, _ := domain.CreateMaterialTypeAgrochemical(domain.ChemicalTypeFertilizer)
+
+	var tests = []interface{}{
+		domain.MaterialCreated{
+			EventVersion: domain.MaterialCreatedEventVersion, UID: uid, Name: "Bayam Lu Hsieh", PricePerUnit: price,
+			Type: mts, Quantity: quantity, ExpirationDate: &date, Notes: &notes, ProducedBy: &producedBy,
+			IsExpense: &isExpense, SupplierID: &otherUID, ExternalID: &notes, CreatedDate: date,
+		},
+		domain.MaterialProduced{MaterialUID: uid, CropUID: otherUID, Quantity: quantity, ProducedDate: date},
+		domain.MaterialNameChanged{MaterialUID: uid, Name: "Kangkung", ChangedAt: date, ChangedBy: otherUID},
+		domain.MaterialPriceChanged{MaterialUID: uid, Price: price, ChangedAt: date, ChangedBy: otherUID},
+		domain.MaterialQuantityChanged{MaterialUID: uid, MaterialTypeCode: domain.MaterialTypeSeedCode, Quantity: quantity, ChangedAt: date, ChangedBy: otherUID},
+		domain.MaterialQuantityConsumed{MaterialUID: uid, Amount: 2.5},
+		domain.MaterialQuantityRestocked{MaterialUID: uid, Amount: 3, ExpirationDate: &date},
+		domain.MaterialQuantityReserved{MaterialUID: uid, Amount: 4},
+		domain.MaterialReservationReleased{MaterialUID: uid, Amount: 1},
+		domain.MaterialLotAdded{MaterialUID: uid, Lot: domain.MaterialLot{LotNumber: "LOT-1", Quantity: 10, ExpirationDate: &date}},
+		domain.MaterialLotConsumed{MaterialUID: uid, LotNumber: "LOT-1", Amount: 2},
+		domain.MaterialLowStockThresholdChanged{MaterialUID: uid, Threshold: &threshold},
+		domain.MaterialTypeChanged{MaterialUID: uid, MaterialType: mta, QuantityUnit: domain.MaterialQuantityUnit{Code: domain.MaterialUnitBottles, Label: "Bottles"}, ChangedAt: date, ChangedBy: otherUID},
+		domain.MaterialPlantTypeChanged{MaterialUID: uid, PlantType: domain.GetPlantType(domain.PlantTypeHerb), ChangedAt: date, ChangedBy: otherUID},
+		domain.MaterialExpirationDateChanged{MaterialUID: uid, ExpirationDate: &date, ChangedAt: date, ChangedBy: otherUID},
+		domain.MaterialNotesChanged{MaterialUID: uid, Notes: &notes, ChangedAt: date, ChangedBy: otherUID},
+		domain.MaterialProducedByChanged{MaterialUID: uid, ProducedBy: producedBy, ChangedAt: date, ChangedBy: otherUID},
+		domain.MaterialSupplierChanged{MaterialUID: uid, SupplierID: &otherUID, ChangedAt: date, ChangedBy: otherUID},
+		domain.MaterialExpired{MaterialUID: uid, ExpiredDate: date},
+		domain.MaterialMerged{MaterialUID: uid, MergedMaterialUID: otherUID, Amount: 5, ExpirationDate: &date},
+		domain.MaterialArchived{MaterialUID: uid, ArchivedDate: date},
+		domain.MaterialUnarchived{MaterialUID: uid},
+		domain.MaterialTagAdded{MaterialUID: uid, Tag: "organic"},
+		domain.MaterialTagRemoved{MaterialUID: uid, Tag: "organic"},
+		domain.MaterialSupplierSKUSet{MaterialUID: uid, SupplierID: otherUID, SKU: "TS-0042"},
+		domain.MaterialSupplierSKURemoved{MaterialUID: uid, SupplierID: otherUID},
+		domain.MaterialComponentAdded{MaterialUID: uid, Component: domain.MaterialComponent{MaterialUID: otherUID, Quantity: 2}},
+		domain.MaterialComponentRemoved{MaterialUID: uid, ComponentUID: otherUID},
+		domain.MaterialAttachmentAdded{MaterialUID: uid, Attachment: domain.Attachment{Key: "materials/label.jpg", ContentType: "image/jpeg"}},
+		domain.MaterialAttachmentRemoved{MaterialUID: uid, Key: "materials/label.jpg"},
+	}
+
+	for _, test := range tests {
+		// When
+		b, err := MarshalMaterialEvent(test)
+		assert.Nil(t, err)
+
+		event, err := UnmarshalMaterialEvent(b)
+
+		// Then
+		assert.Nil(t, err, string(b))
+		assert.Equal(t, test, event, string(b))
+	}
+}
+
+func TestUnmarshalUnknownMaterialEvent(t *testing.T) {
+	// When
+	_, err := UnmarshalMaterialEvent([]byte(`{"EventName": "MaterialPainted", "EventData": {}}`))
+
+	// Then
+	assert.NotNil(t, err)
+}
+
+func TestMaterialLockerReleasesUnlockedMaterials(t *testing.T) {
+	// Given
+	locker := MaterialLocker{}
+	uid, _ := uuid.NewV4()
+	otherUID, _ := uuid.NewV4()
+
+	lockCount := func() int {
+		locker.mu.Lock()
+		defer locker.mu.Unlock()
+
+		return len(locker.locks)
+	}
+
+	// When
+	unlock := locker.Lock(uid)
+	done := make(chan struct{})
+	go func() {
+		locker.Lock(uid)()
+		close(done)
+	}()
+	locker.Lock(otherUID)()
+
+	// Then
+	assert.Equal(t, 1, lockCount())
+
+	// When
+	unlock()
+	<-done
+
+	// Then
+	assert.Equal(t, 0, lockCount())
+}
+
+func TestNewMaterialFromHistoryDatesOldPriceChanges(t *testing.T) {
+	// Given
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	created := material.CreatedDate
+	changed := created.AddDate(0, 1, 0)
+	newPrice := domain.PricePerUnit{Amount: "15.00", CurrencyCode: domain.MoneyEUR}
+
+	events := []storage.MaterialEvent{
+		{MaterialUID: material.UID, Version: 1, CreatedDate: created, Event: material.UncommittedChanges[0]},
+		{MaterialUID: material.UID, Version: 2, CreatedDate: changed, Event: domain.MaterialPriceChanged{MaterialUID: material.UID, Price: newPrice}},
+	}
+
+	// When
+	replayed := NewMaterialFromHistory(events)
+	before, _ := replayed.PriceAt(changed.AddDate(0, 0, -1))
+	after, _ := replayed.PriceAt(changed)
+
+	// Then
+	assert.Equal(t, material.PricePerUnit, before)
+	assert.Equal(t, newPrice, after)
+	assert.Equal(t, changed, replayed.PriceHistory()[1].EffectiveFrom)
+}
@@ -0,0 +1,509 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/decoder"
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/repository"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	sqlite3 "github.com/mattn/go-sqlite3"
+	uuid "github.com/satori/go.uuid"
+)
+
+type MaterialRepositorySqlite struct {
+	DB *sql.DB
+
+	locks repository.MaterialLocker
+}
+
+func NewMaterialRepositorySqlite(db *sql.DB) repository.MaterialRepository {
+	return &MaterialRepositorySqlite{DB: db}
+}
+
+func (f *MaterialRepositorySqlite) Save(material *domain.Material, expectedVersion int) <-chan error {
+	result := make(chan error)
+
+	go func() {
+		result <- f.save(material, expectedVersion)
+		close(result)
+	}()
+
+	return result
+}
+
+// save checks the stored version and inserts the events in one transaction,
+// so two stale writes can't both pass the check.
+func (f *MaterialRepositorySqlite) save(material *domain.Material, expectedVersion int) error {
+	tx, err := f.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = insertMaterialEvents(tx, material, expectedVersion)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	material.MarkChangesCommitted()
+
+	return nil
+}
+
+// FindOrSave saves the material unless another material was already created
+// with the same ExternalID, in which case that material is returned instead.
+func (f *MaterialRepositorySqlite) FindOrSave(material *domain.Material) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		found, err := f.findOrSave(material)
+		result <- repository.RepositoryResult{Result: found, Error: err}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositorySqlite) findOrSave(material *domain.Material) (*domain.Material, error) {
+	if material.ExternalID == nil {
+		return material, f.save(material, material.BaseVersion())
+	}
+
+	existing, err := f.findByExternalID(*material.ExternalID)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	err = f.save(material, material.BaseVersion())
+	if err == repository.ErrDuplicateExternalID {
+		// Another request saved a material with the same external ID since the lookup.
+		return f.findByExternalID(*material.ExternalID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return material, nil
+}
+
+// findByExternalID returns the material created with externalID, or nil when there is none.
+func (f *MaterialRepositorySqlite) findByExternalID(externalID string) (*domain.Material, error) {
+	materialUID := ""
+	err := f.DB.QueryRow("SELECT MATERIAL_UID FROM MATERIAL_EXTERNAL_ID WHERE EXTERNAL_ID = ?", externalID).Scan(&materialUID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := uuid.FromString(materialUID)
+	if err != nil {
+		return nil, err
+	}
+
+	found := <-f.FindByID(uid)
+	if found.Error != nil {
+		return nil, found.Error
+	}
+
+	material, ok := found.Result.(*domain.Material)
+	if !ok {
+		return nil, errors.New("Internal server error. Error type assertion")
+	}
+
+	return material, nil
+}
+
+// insertMaterialEvents checks the stored version and inserts the uncommitted events.
+// The caller owns the transaction.
+func insertMaterialEvents(tx *sql.Tx, material *domain.Material, expectedVersion int) error {
+	storedVersion := 0
+	err := tx.QueryRow("SELECT COALESCE(MAX(VERSION), 0) FROM MATERIAL_EVENT WHERE MATERIAL_UID = ?", material.UID).Scan(&storedVersion)
+	if err != nil {
+		return err
+	}
+
+	if storedVersion != expectedVersion {
+		return repository.ErrConcurrentModification
+	}
+
+	// The primary key of MATERIAL_EXTERNAL_ID keeps external IDs unique,
+	// also between writers that both passed a lookup.
+	if expectedVersion == 0 && material.ExternalID != nil {
+		_, err = tx.Exec(`INSERT INTO MATERIAL_EXTERNAL_ID (EXTERNAL_ID, MATERIAL_UID) VALUES (?, ?)`,
+			*material.ExternalID, material.UID)
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicateExternalID
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	latestVersion := expectedVersion
+	for _, v := range material.UncommittedChanges {
+		latestVersion++
+
+		e, err := repository.MarshalMaterialEvent(v)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO MATERIAL_EVENT (MATERIAL_UID, VERSION, CREATED_DATE, EVENT) VALUES (?, ?, ?, ?)`,
+			material.UID, latestVersion, time.Now().Format(time.RFC3339), e)
+		if isUniqueViolation(err) {
+			return repository.ErrConcurrentModification
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Like the in memory repository, the material is snapshotted every MaterialSnapshotInterval events
+	if latestVersion/repository.MaterialSnapshotInterval > expectedVersion/repository.MaterialSnapshotInterval {
+		snap, err := repository.MarshalMaterialSnapshot(material.ToSnapshot())
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT OR REPLACE INTO MATERIAL_SNAPSHOT (MATERIAL_UID, VERSION, SNAPSHOT) VALUES (?, ?, ?)`,
+			material.UID, latestVersion, snap)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isUniqueViolation tells whether err is SQLite rejecting a row that breaks a UNIQUE or PRIMARY KEY constraint.
+func isUniqueViolation(err error) bool {
+	sqliteErr, ok := err.(sqlite3.Error)
+
+	return ok && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+func (f *MaterialRepositorySqlite) FindByID(uid uuid.UUID) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		snap, ok, err := f.findSnapshot(uid)
+		if err != nil {
+			result <- repository.RepositoryResult{Error: err}
+			close(result)
+			return
+		}
+
+		// Only the events stored after the snapshot need replaying
+		rows, err := f.DB.Query("SELECT * FROM MATERIAL_EVENT WHERE MATERIAL_UID = ? AND VERSION > ? ORDER BY VERSION ASC", uid, snap.Version)
+		if err != nil {
+			result <- repository.RepositoryResult{Error: err}
+			close(result)
+			return
+		}
+
+		events, err := scanMaterialEvents(rows)
+		if err != nil {
+			result <- repository.RepositoryResult{Error: err}
+			close(result)
+			return
+		}
+
+		if ok {
+			result <- repository.RepositoryResult{Result: repository.NewMaterialFromSnapshot(snap, events)}
+			close(result)
+			return
+		}
+
+		if len(events) == 0 {
+			result <- repository.RepositoryResult{Error: repository.ErrMaterialNotFound}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: repository.NewMaterialFromHistory(events)}
+		close(result)
+	}()
+
+	return result
+}
+
+// findSnapshot returns the latest snapshot of the material, and false when it has none yet.
+func (f *MaterialRepositorySqlite) findSnapshot(uid uuid.UUID) (domain.MaterialSnapshot, bool, error) {
+	data := []byte{}
+	err := f.DB.QueryRow("SELECT SNAPSHOT FROM MATERIAL_SNAPSHOT WHERE MATERIAL_UID = ?", uid).Scan(&data)
+	if err == sql.ErrNoRows {
+		return domain.MaterialSnapshot{}, false, nil
+	}
+	if err != nil {
+		return domain.MaterialSnapshot{}, false, err
+	}
+
+	snap, err := repository.UnmarshalMaterialSnapshot(data)
+	if err != nil {
+		return domain.MaterialSnapshot{}, false, err
+	}
+
+	return snap, true, nil
+}
+
+func (f *MaterialRepositorySqlite) FindAll() <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		rows, err := f.DB.Query("SELECT * FROM MATERIAL_EVENT ORDER BY ID ASC")
+		if err != nil {
+			result <- repository.RepositoryResult{Error: err}
+			close(result)
+			return
+		}
+
+		events, err := scanMaterialEvents(rows)
+		if err != nil {
+			result <- repository.RepositoryResult{Error: err}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: repository.NewMaterialsFromHistory(events)}
+		close(result)
+	}()
+
+	return result
+}
+
+// FindBySupplier replays every material, since the supplier is only known from the events.
+func (f *MaterialRepositorySqlite) FindBySupplier(supplierUID uuid.UUID) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		all := <-f.FindAll()
+		if all.Error != nil {
+			result <- repository.RepositoryResult{Error: all.Error}
+			close(result)
+			return
+		}
+
+		materials, ok := all.Result.([]domain.Material)
+		if !ok {
+			result <- repository.RepositoryResult{Error: errors.New("Internal server error. Error type assertion")}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: repository.FilterMaterialsBySupplier(materials, supplierUID)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositorySqlite) FindByTag(tag string) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		all := <-f.FindAll()
+		if all.Error != nil {
+			result <- repository.RepositoryResult{Error: all.Error}
+			close(result)
+			return
+		}
+
+		materials, ok := all.Result.([]domain.Material)
+		if !ok {
+			result <- repository.RepositoryResult{Error: errors.New("Internal server error. Error type assertion")}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: repository.FilterMaterialsByTag(materials, tag)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositorySqlite) FindCreatedBetween(start, end time.Time) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		all := <-f.FindAll()
+		if all.Error != nil {
+			result <- repository.RepositoryResult{Error: all.Error}
+			close(result)
+			return
+		}
+
+		materials, ok := all.Result.([]domain.Material)
+		if !ok {
+			result <- repository.RepositoryResult{Error: errors.New("Internal server error. Error type assertion")}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: repository.FilterMaterialsCreatedBetween(materials, start, end)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositorySqlite) FindByPriceRange(currency string, min, max float64) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		all := <-f.FindAll()
+		if all.Error != nil {
+			result <- repository.RepositoryResult{Error: all.Error}
+			close(result)
+			return
+		}
+
+		materials, ok := all.Result.([]domain.Material)
+		if !ok {
+			result <- repository.RepositoryResult{Error: errors.New("Internal server error. Error type assertion")}
+			close(result)
+			return
+		}
+
+		filtered, err := repository.FilterMaterialsByPriceRange(materials, currency, min, max)
+		if err != nil {
+			result <- repository.RepositoryResult{Error: err}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: filtered}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositorySqlite) FindIncomplete() <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		all := <-f.FindAll()
+		if all.Error != nil {
+			result <- repository.RepositoryResult{Error: all.Error}
+			close(result)
+			return
+		}
+
+		materials, ok := all.Result.([]domain.Material)
+		if !ok {
+			result <- repository.RepositoryResult{Error: errors.New("Internal server error. Error type assertion")}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: repository.FilterIncompleteMaterials(materials)}
+		close(result)
+	}()
+
+	return result
+}
+
+func (f *MaterialRepositorySqlite) FindBySKU(supplierID uuid.UUID, sku string) <-chan repository.RepositoryResult {
+	result := make(chan repository.RepositoryResult)
+
+	go func() {
+		all := <-f.FindAll()
+		if all.Error != nil {
+			result <- repository.RepositoryResult{Error: all.Error}
+			close(result)
+			return
+		}
+
+		materials, ok := all.Result.([]domain.Material)
+		if !ok {
+			result <- repository.RepositoryResult{Error: errors.New("Internal server error. Error type assertion")}
+			close(result)
+			return
+		}
+
+		result <- repository.RepositoryResult{Result: repository.FilterMaterialsBySKU(materials, supplierID, sku)}
+		close(result)
+	}()
+
+	return result
+}
+
+func scanMaterialEvents(rows *sql.Rows) ([]storage.MaterialEvent, error) {
+	defer rows.Close()
+
+	events := []storage.MaterialEvent{}
+
+	rowsData := struct {
+		ID          int
+		MaterialUID string
+		Version     int
+		CreatedDate string
+		Event       []byte
+	}{}
+
+	for rows.Next() {
+		err := rows.Scan(&rowsData.ID, &rowsData.MaterialUID, &rowsData.Version, &rowsData.CreatedDate, &rowsData.Event)
+		if err != nil {
+			return nil, err
+		}
+
+		wrapper := decoder.MaterialEventWrapper{}
+		err = json.Unmarshal(rowsData.Event, &wrapper)
+		if err != nil {
+			return nil, err
+		}
+
+		materialUID, err := uuid.FromString(rowsData.MaterialUID)
+		if err != nil {
+			return nil, err
+		}
+
+		createdDate, err := time.Parse(time.RFC3339, rowsData.CreatedDate)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, storage.MaterialEvent{
+			MaterialUID: materialUID,
+			Version:     rowsData.Version,
+			CreatedDate: createdDate,
+			Event:       wrapper.EventData,
+		})
+	}
+
+	return events, rows.Err()
+}
+
+func (f *MaterialRepositorySqlite) WithMaterial(uid uuid.UUID, fn func(*domain.Material) error) error {
+	unlock := f.locks.Lock(uid)
+	defer unlock()
+
+	result := <-f.FindByID(uid)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	material, ok := result.Result.(*domain.Material)
+	if !ok {
+		return errors.New("Internal server error. Error type assertion")
+	}
+
+	err := fn(material)
+	if err != nil {
+		return err
+	}
+
+	return <-f.Save(material, material.BaseVersion())
+}
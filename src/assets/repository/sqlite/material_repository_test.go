@@ -0,0 +1,174 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/repository"
+	_ "github.com/mattn/go-sqlite3"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func openMaterialEventDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every connection to :memory: opens its own empty database
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE "MATERIAL_EVENT" (
+		"ID" INTEGER PRIMARY KEY,
+		"MATERIAL_UID" BLOB,
+		"VERSION" INTEGER,
+		"CREATED_DATE" TEXT,
+		"EVENT" BLOB
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec(`CREATE UNIQUE INDEX "MATERIAL_EVENT_MATERIAL_UID_VERSION_UNIQUE_INDEX" ON "MATERIAL_EVENT" ("MATERIAL_UID", "VERSION")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE "MATERIAL_SNAPSHOT" (
+		"MATERIAL_UID" BLOB PRIMARY KEY,
+		"VERSION" INTEGER,
+		"SNAPSHOT" BLOB
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE "MATERIAL_EXTERNAL_ID" (
+		"EXTERNAL_ID" TEXT PRIMARY KEY,
+		"MATERIAL_UID" BLOB
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestMaterialSqliteFindByIDNotFound(t *testing.T) {
+	// Given
+	db := openMaterialEventDB(t)
+	defer db.Close()
+
+	repo := NewMaterialRepositorySqlite(db)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	assert.Nil(t, <-repo.Save(material, material.BaseVersion()))
+
+	unknownUID, _ := uuid.NewV4()
+
+	// When
+	found := <-repo.FindByID(material.UID)
+	missing := <-repo.FindByID(unknownUID)
+
+	// Then
+	assert.Nil(t, found.Error)
+	assert.Equal(t, material.UID, found.Result.(*domain.Material).UID)
+	assert.True(t, errors.Is(missing.Error, repository.ErrMaterialNotFound))
+}
+
+func TestMaterialSqliteFindOrSave(t *testing.T) {
+	// Given
+	db := openMaterialEventDB(t)
+	defer db.Close()
+
+	repo := NewMaterialRepositorySqlite(db)
+
+	externalID := "seed-import-001"
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	first, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, &externalID)
+	retry, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, &externalID)
+	duplicate, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, &externalID)
+
+	// When
+	saved := <-repo.FindOrSave(first)
+	found := <-repo.FindOrSave(retry)
+	err := <-repo.Save(duplicate, duplicate.BaseVersion())
+
+	// Then
+	assert.Nil(t, saved.Error)
+	assert.Equal(t, first.UID, saved.Result.(*domain.Material).UID)
+	assert.Nil(t, found.Error)
+	assert.Equal(t, first.UID, found.Result.(*domain.Material).UID)
+	assert.Equal(t, repository.ErrDuplicateExternalID, err)
+
+	count := 0
+	assert.Nil(t, db.QueryRow("SELECT COUNT(DISTINCT MATERIAL_UID) FROM MATERIAL_EVENT").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestMaterialEventSqliteSaveStaleVersion(t *testing.T) {
+	// Given
+	db := openMaterialEventDB(t)
+	defer db.Close()
+
+	repo := NewMaterialEventRepositorySqlite(db)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	assert.Nil(t, <-repo.Save(material.UID, material.BaseVersion(), material.UncommittedChanges))
+
+	// When
+	err := <-repo.Save(material.UID, material.BaseVersion(), material.UncommittedChanges)
+
+	// Then
+	assert.Equal(t, repository.ErrConcurrentModification, err)
+}
+
+func TestMaterialSqliteSnapshot(t *testing.T) {
+	// Given
+	db := openMaterialEventDB(t)
+	defer db.Close()
+
+	repo := NewMaterialRepositorySqlite(db)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitKilogram, nil, nil, nil, nil, nil, nil)
+	material.ChangeName("Bayam Hijau", time.Now(), uuid.Nil)
+	material.ChangePricePerUnit("13.50", domain.MoneyEUR, time.Now(), uuid.Nil)
+	for i := 0; i < repository.MaterialSnapshotInterval; i++ {
+		material.Reserve(0.5)
+	}
+
+	// When
+	err := <-repo.Save(material, material.BaseVersion())
+
+	material.ReleaseReservation(1)
+	err2 := <-repo.Save(material, material.BaseVersion())
+
+	result := <-repo.FindByID(material.UID)
+	all := <-repo.FindAll()
+
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, err2)
+	assert.Nil(t, result.Error)
+	assert.Nil(t, all.Error)
+
+	snapshotVersion := 0
+	assert.Nil(t, db.QueryRow("SELECT VERSION FROM MATERIAL_SNAPSHOT WHERE MATERIAL_UID = ?", material.UID).Scan(&snapshotVersion))
+	assert.Equal(t, repository.MaterialSnapshotInterval+3, snapshotVersion)
+
+	found := result.Result.(*domain.Material)
+	replayed := all.Result.([]domain.Material)
+	assert.Equal(t, replayed[0], *found)
+	assert.Equal(t, material.Version, found.Version)
+	assert.Equal(t, material.Reserved, found.Reserved)
+	assert.Equal(t, mts, found.Type)
+	assert.Len(t, found.ChangeHistory(), 2)
+	assert.Len(t, found.PriceHistory(), 2)
+}
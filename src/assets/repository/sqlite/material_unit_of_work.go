@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/repository"
+)
+
+type MaterialUnitOfWorkSqlite struct {
+	DB *sql.DB
+
+	materials []*domain.Material
+}
+
+func NewMaterialUnitOfWorkSqlite(db *sql.DB) repository.MaterialUnitOfWork {
+	return &MaterialUnitOfWorkSqlite{DB: db}
+}
+
+func (u *MaterialUnitOfWorkSqlite) Register(material *domain.Material) {
+	for _, v := range u.materials {
+		if v == material {
+			return
+		}
+	}
+
+	u.materials = append(u.materials, material)
+}
+
+// Commit inserts the events, and the snapshots that are due, of every registered material
+// in one transaction, which is rolled back when one of the materials fails its version check or insert.
+func (u *MaterialUnitOfWorkSqlite) Commit() error {
+	tx, err := u.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, v := range u.materials {
+		err = insertMaterialEvents(tx, v, v.BaseVersion())
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	for _, v := range u.materials {
+		v.MarkChangesCommitted()
+	}
+
+	u.materials = nil
+
+	return nil
+}
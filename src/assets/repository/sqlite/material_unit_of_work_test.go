@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaterialUnitOfWorkSqliteCommit(t *testing.T) {
+	// Given
+	db := openMaterialEventDB(t)
+	defer db.Close()
+
+	uow := NewMaterialUnitOfWorkSqlite(db)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	bayam, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitKilogram, nil, nil, nil, nil, nil, nil)
+	for i := 0; i < repository.MaterialSnapshotInterval; i++ {
+		bayam.Reserve(0.5)
+	}
+	kangkung, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	uow.Register(bayam)
+	uow.Register(kangkung)
+	err := uow.Commit()
+
+	// Then
+	assert.Nil(t, err)
+	assert.Empty(t, bayam.UncommittedChanges)
+	assert.Empty(t, kangkung.UncommittedChanges)
+
+	events := 0
+	assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM MATERIAL_EVENT").Scan(&events))
+	assert.Equal(t, repository.MaterialSnapshotInterval+2, events)
+
+	snapshotVersion := 0
+	assert.Nil(t, db.QueryRow("SELECT VERSION FROM MATERIAL_SNAPSHOT WHERE MATERIAL_UID = ?", bayam.UID).Scan(&snapshotVersion))
+	assert.Equal(t, repository.MaterialSnapshotInterval+1, snapshotVersion)
+}
+
+func TestMaterialUnitOfWorkSqliteRollback(t *testing.T) {
+	// Given
+	db := openMaterialEventDB(t)
+	defer db.Close()
+
+	repo := NewMaterialRepositorySqlite(db)
+	uow := NewMaterialUnitOfWorkSqlite(db)
+
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	bayam, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	kangkung, _ := domain.CreateMaterial("Kangkung", "8", domain.MoneyEUR, mts, 10, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+	<-repo.Save(kangkung, kangkung.BaseVersion())
+
+	stale := kangkung.Clone()
+	kangkung.ConsumeQuantity(2)
+	<-repo.Save(kangkung, kangkung.BaseVersion())
+
+	stale.ConsumeQuantity(3)
+
+	// When
+	uow.Register(bayam)
+	uow.Register(stale)
+	err := uow.Commit()
+
+	// Then
+	assert.Equal(t, repository.ErrConcurrentModification, err)
+	assert.Len(t, bayam.UncommittedChanges, 1)
+	assert.Len(t, stale.UncommittedChanges, 1)
+
+	events := 0
+	assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM MATERIAL_EVENT WHERE MATERIAL_UID = ?", bayam.UID).Scan(&events))
+	assert.Equal(t, 0, events)
+
+	result := <-repo.FindByID(bayam.UID)
+	assert.Equal(t, repository.ErrMaterialNotFound, result.Error)
+}
@@ -46,6 +46,9 @@ type FarmServer struct {
 	AreaReadQuery       query.AreaReadQuery
 	AreaService         domain.AreaService
 	MaterialEventRepo   repository.MaterialEventRepository
+	MaterialRepo        repository.MaterialRepository
+	MaterialService     service.MaterialService
+	MaterialSubscribers *service.SubscriberRegistry
 	MaterialEventQuery  query.MaterialEventQuery
 	MaterialReadRepo    repository.MaterialReadRepository
 	MaterialReadQuery   query.MaterialReadQuery
@@ -91,6 +94,7 @@ func NewFarmServer(
 		farmServer.ReservoirReadQuery = queryInMem.NewReservoirReadQueryInMemory(reservoirReadStorage)
 
 		farmServer.MaterialEventRepo = repoInMem.NewMaterialEventRepositoryInMemory(materialEventStorage)
+		farmServer.MaterialRepo = repoInMem.NewMaterialRepositoryInMemory(materialEventStorage)
 		farmServer.MaterialEventQuery = queryInMem.NewMaterialEventQueryInMemory(materialEventStorage)
 		farmServer.MaterialReadRepo = repoInMem.NewMaterialReadRepositoryInMemory(materialReadStorage)
 		farmServer.MaterialReadQuery = queryInMem.NewMaterialReadQueryInMemory(materialReadStorage)
@@ -125,6 +129,7 @@ func NewFarmServer(
 		farmServer.ReservoirReadQuery = querySqlite.NewReservoirReadQuerySqlite(db)
 
 		farmServer.MaterialEventRepo = repoSqlite.NewMaterialEventRepositorySqlite(db)
+		farmServer.MaterialRepo = repoSqlite.NewMaterialRepositorySqlite(db)
 		farmServer.MaterialEventQuery = querySqlite.NewMaterialEventQuerySqlite(db)
 		farmServer.MaterialReadRepo = repoSqlite.NewMaterialReadRepositorySqlite(db)
 		farmServer.MaterialReadQuery = querySqlite.NewMaterialReadQuerySqlite(db)
@@ -159,6 +164,7 @@ func NewFarmServer(
 		farmServer.ReservoirReadQuery = queryMysql.NewReservoirReadQueryMysql(db)
 
 		farmServer.MaterialEventRepo = repoMysql.NewMaterialEventRepositoryMysql(db)
+		farmServer.MaterialRepo = repoMysql.NewMaterialRepositoryMysql(db)
 		farmServer.MaterialEventQuery = queryMysql.NewMaterialEventQueryMysql(db)
 		farmServer.MaterialReadRepo = repoMysql.NewMaterialReadRepositoryMysql(db)
 		farmServer.MaterialReadQuery = queryMysql.NewMaterialReadQueryMysql(db)
@@ -177,6 +183,15 @@ func NewFarmServer(
 		}
 	}
 
+	// Material events reach the event bus through MaterialSubscribers,
+	// which MaterialService only publishes to once the events are saved.
+	farmServer.MaterialSubscribers = &service.SubscriberRegistry{}
+	farmServer.MaterialService = service.MaterialService{
+		MaterialRepo:       farmServer.MaterialRepo,
+		MaterialEventQuery: farmServer.MaterialEventQuery,
+		EventPublisher:     farmServer.MaterialSubscribers,
+	}
+
 	farmServer.InitSubscriber()
 
 	return farmServer, nil
@@ -206,14 +221,25 @@ func (s *FarmServer) InitSubscriber() {
 	s.EventBus.Subscribe("AreaNoteAdded", s.SaveToAreaReadModel)
 	s.EventBus.Subscribe("AreaNoteRemoved", s.SaveToAreaReadModel)
 
+	s.MaterialSubscribers.Subscribe(func(event interface{}) {
+		s.EventBus.Publish(structhelper.GetName(event), event)
+	})
 	s.EventBus.Subscribe("MaterialCreated", s.SaveToMaterialReadModel)
 	s.EventBus.Subscribe("MaterialNameChanged", s.SaveToMaterialReadModel)
 	s.EventBus.Subscribe("MaterialPriceChanged", s.SaveToMaterialReadModel)
 	s.EventBus.Subscribe("MaterialQuantityChanged", s.SaveToMaterialReadModel)
+	s.EventBus.Subscribe("MaterialQuantityConsumed", s.SaveToMaterialReadModel)
+	s.EventBus.Subscribe("MaterialQuantityRestocked", s.SaveToMaterialReadModel)
+	s.EventBus.Subscribe("MaterialLotAdded", s.SaveToMaterialReadModel)
+	s.EventBus.Subscribe("MaterialLotConsumed", s.SaveToMaterialReadModel)
 	s.EventBus.Subscribe("MaterialTypeChanged", s.SaveToMaterialReadModel)
+	s.EventBus.Subscribe("MaterialPlantTypeChanged", s.SaveToMaterialReadModel)
 	s.EventBus.Subscribe("MaterialExpirationDateChanged", s.SaveToMaterialReadModel)
 	s.EventBus.Subscribe("MaterialNotesChanged", s.SaveToMaterialReadModel)
 	s.EventBus.Subscribe("MaterialProducedByChanged", s.SaveToMaterialReadModel)
+	s.EventBus.Subscribe("MaterialMerged", s.SaveToMaterialReadModel)
+	s.EventBus.Subscribe("MaterialArchived", s.SaveToMaterialReadModel)
+	s.EventBus.Subscribe("MaterialUnarchived", s.SaveToMaterialReadModel)
 
 }
 
@@ -224,6 +250,8 @@ func (s *FarmServer) Mount(g *echo.Group) {
 	g.GET("/inventories/materials/simple", s.GetMaterialsSimple)
 	g.GET("/inventories/plant_types", s.GetInventoryPlantTypes)
 	g.GET("/inventories/materials/available_plant_type", s.GetAvailableMaterialPlantType)
+	g.GET("/inventories/materials/quantity_units", s.GetMaterialQuantityUnits)
+	g.GET("/inventories/materials/schema", s.GetMaterialSchema)
 	g.POST("/inventories/materials/:type", s.SaveMaterial)
 	g.PUT("/inventories/materials/:type/:id", s.UpdateMaterial)
 	g.GET("/inventories/materials/:id", s.GetMaterialByID)
@@ -1270,6 +1298,25 @@ func (s *FarmServer) GetInventoryPlantTypes(c echo.Context) error {
 	return c.JSON(http.StatusOK, data)
 }
 
+func (s *FarmServer) GetMaterialQuantityUnits(c echo.Context) error {
+	data := make(map[string][]domain.MaterialQuantityUnit)
+
+	materialType := strings.ToUpper(c.QueryParam("type"))
+
+	units, ok := domain.LocalizedMaterialQuantityUnits(materialType, c.QueryParam("locale"))
+	if !ok {
+		return Error(c, NewRequestValidationError(INVALID_OPTION, "type"))
+	}
+
+	data["data"] = units
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (s *FarmServer) GetMaterialSchema(c echo.Context) error {
+	return c.JSON(http.StatusOK, MaterialCreateSchemas())
+}
+
 func (s *FarmServer) GetMaterials(c echo.Context) error {
 	materialType := c.QueryParam("type")
 	materialTypeDetail := c.QueryParam("type_detail")
@@ -1281,7 +1328,12 @@ func (s *FarmServer) GetMaterials(c echo.Context) error {
 		return Error(c, err)
 	}
 
-	queryResult := <-s.MaterialReadQuery.FindAll(materialType, materialTypeDetail, pageInt, limitInt)
+	includeArchived, err := parseIncludeArchived(c)
+	if err != nil {
+		return Error(c, err)
+	}
+
+	queryResult := <-s.MaterialReadQuery.FindAll(materialType, materialTypeDetail, includeArchived, pageInt, limitInt)
 	if queryResult.Error != nil {
 		return Error(c, queryResult.Error)
 	}
@@ -1296,7 +1348,7 @@ func (s *FarmServer) GetMaterials(c echo.Context) error {
 		materials = append(materials, MapToMaterialFromRead(v))
 	}
 
-	queryResult = <-s.MaterialReadQuery.CountAll(materialType, materialTypeDetail)
+	queryResult = <-s.MaterialReadQuery.CountAll(materialType, materialTypeDetail, includeArchived)
 	if queryResult.Error != nil {
 		return Error(c, queryResult.Error)
 	}
@@ -1314,11 +1366,31 @@ func (s *FarmServer) GetMaterials(c echo.Context) error {
 	return c.JSON(http.StatusOK, data)
 }
 
+// parseIncludeArchived reads the optional include_archived query param, which defaults to false.
+func parseIncludeArchived(c echo.Context) (bool, error) {
+	includeArchived := c.QueryParam("include_archived")
+	if includeArchived == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(includeArchived)
+	if err != nil {
+		return false, NewRequestValidationError(PARSE_FAILED, "include_archived")
+	}
+
+	return b, nil
+}
+
 func (s *FarmServer) GetMaterialsSimple(c echo.Context) error {
 	materialType := c.QueryParam("type")
 	materialTypeDetail := c.QueryParam("type_detail")
 
-	queryResult := <-s.MaterialReadQuery.FindAll(materialType, materialTypeDetail, 0, 0)
+	includeArchived, err := parseIncludeArchived(c)
+	if err != nil {
+		return Error(c, err)
+	}
+
+	queryResult := <-s.MaterialReadQuery.FindAll(materialType, materialTypeDetail, includeArchived, 0, 0)
 	if queryResult.Error != nil {
 		return Error(c, queryResult.Error)
 	}
@@ -1338,19 +1410,29 @@ func (s *FarmServer) SaveMaterial(c echo.Context) error {
 	data := make(map[string]Material)
 
 	materialTypeParam := c.Param("type")
-	name := c.FormValue("name")
 
-	plantType := c.FormValue("plant_type")
-	chemicalType := c.FormValue("chemical_type")
-	containerType := c.FormValue("container_type")
+	req, err := ParseMaterialRequest(c)
+	if err != nil {
+		return Error(c, err)
+	}
+
+	name := req.Name
+
+	plantType := req.PlantType
+	packaging := req.Packaging
+	chemicalType := req.ChemicalType
+	containerType := req.ContainerType
 
-	pricePerUnit := c.FormValue("price_per_unit")
-	currencyCode := c.FormValue("currency_code")
-	quantity := c.FormValue("quantity")
-	quantityUnit := c.FormValue("quantity_unit")
-	expirationDate := c.FormValue("expiration_date")
-	notes := c.FormValue("notes")
-	producedBy := c.FormValue("produced_by")
+	pricePerUnit := req.PricePerUnit
+	currencyCode := req.CurrencyCode
+	quantity := req.Quantity
+	quantityUnit := req.QuantityUnit
+	expirationDate := req.ExpirationDate
+	notes := req.Notes
+	producedBy := req.ProducedBy
+	isExpense := req.IsExpense
+	supplierID := req.SupplierID
+	externalID := req.ExternalID
 
 	// Validate //
 	q, err := strconv.ParseFloat(quantity, 32)
@@ -1375,9 +1457,38 @@ func (s *FarmServer) SaveMaterial(c echo.Context) error {
 
 	var pb *string
 	if producedBy != "" {
+		if domain.GetProducedBySource(producedBy) == (domain.ProducedBySource{}) {
+			return Error(c, NewRequestValidationError(INVALID_OPTION, "produced_by"))
+		}
+
 		pb = &producedBy
 	}
 
+	var ie *bool
+	if isExpense != "" {
+		b, err := strconv.ParseBool(isExpense)
+		if err != nil {
+			return Error(c, NewRequestValidationError(PARSE_FAILED, "is_expense"))
+		}
+
+		ie = &b
+	}
+
+	var sid *uuid.UUID
+	if supplierID != "" {
+		uid, err := uuid.FromString(supplierID)
+		if err != nil {
+			return Error(c, NewRequestValidationError(PARSE_FAILED, "supplier_id"))
+		}
+
+		sid = &uid
+	}
+
+	var eid *string
+	if externalID != "" {
+		eid = &externalID
+	}
+
 	// Process //
 	var mt domain.MaterialType
 	switch materialTypeParam {
@@ -1425,29 +1536,31 @@ func (s *FarmServer) SaveMaterial(c echo.Context) error {
 			return Error(c, NewRequestValidationError(INVALID_OPTION, "plant_type"))
 		}
 
-		mt, err = domain.CreateMaterialTypePlant(pt.Code)
+		if packaging == "" {
+			return Error(c, NewRequestValidationError(REQUIRED, "packaging"))
+		}
+
+		mt, err = domain.CreateMaterialTypePlantWithPackaging(pt.Code, packaging)
 		if err != nil {
-			return Error(c, NewRequestValidationError(INVALID_OPTION, "type"))
+			return Error(c, NewRequestValidationError(INVALID_OPTION, "packaging"))
 		}
 	}
 
 	material, err := domain.CreateMaterial(
 		name, pricePerUnit, currencyCode, mt, float32(q), quantityUnit,
-		expDate, n, pb)
+		expDate, n, pb, ie, sid, eid)
 	if err != nil {
 		return Error(c, err)
 	}
 
-	// Persist //
-	err = <-s.MaterialEventRepo.Save(material.UID, material.Version, material.UncommittedChanges)
+	// Persist and Publish //
+	// A request repeating the external ID of a saved material gets that material back.
+	savedMaterial, err := s.MaterialService.FindOrSaveMaterial(material)
 	if err != nil {
 		return Error(c, err)
 	}
 
-	// Publish //
-	s.publishUncommittedEvents(material)
-
-	data["data"] = MapToMaterial(*material)
+	data["data"] = MapToMaterial(*savedMaterial)
 
 	return c.JSON(http.StatusOK, data)
 }
@@ -1462,18 +1575,25 @@ func (s *FarmServer) UpdateMaterial(c echo.Context) error {
 
 	materialTypeParam := c.Param("type")
 
-	plantType := c.FormValue("plant_type")
-	chemicalType := c.FormValue("chemical_type")
-	containerType := c.FormValue("container_type")
+	req, err := ParseMaterialRequest(c)
+	if err != nil {
+		return Error(c, err)
+	}
 
-	name := c.FormValue("name")
-	pricePerUnit := c.FormValue("price_per_unit")
-	currencyCode := c.FormValue("currency_code")
-	quantity := c.FormValue("quantity")
-	quantityUnit := c.FormValue("quantity_unit")
-	expirationDate := c.FormValue("expiration_date")
-	notes := c.FormValue("notes")
-	producedBy := c.FormValue("produced_by")
+	plantType := req.PlantType
+	packaging := req.Packaging
+	chemicalType := req.ChemicalType
+	containerType := req.ContainerType
+
+	name := req.Name
+	pricePerUnit := req.PricePerUnit
+	currencyCode := req.CurrencyCode
+	quantity := req.Quantity
+	quantityUnit := req.QuantityUnit
+	expirationDate := req.ExpirationDate
+	notes := req.Notes
+	producedBy := req.ProducedBy
+	supplierID := req.SupplierID
 
 	// Validate //
 	if pricePerUnit != "" && currencyCode == "" {
@@ -1509,9 +1629,23 @@ func (s *FarmServer) UpdateMaterial(c echo.Context) error {
 
 	var pb *string
 	if producedBy != "" {
+		if domain.GetProducedBySource(producedBy) == (domain.ProducedBySource{}) {
+			return Error(c, NewRequestValidationError(INVALID_OPTION, "produced_by"))
+		}
+
 		pb = &producedBy
 	}
 
+	var sid *uuid.UUID
+	if supplierID != "" {
+		uid, err := uuid.FromString(supplierID)
+		if err != nil {
+			return Error(c, NewRequestValidationError(PARSE_FAILED, "supplier_id"))
+		}
+
+		sid = &uid
+	}
+
 	queryResult := <-s.MaterialReadQuery.FindByID(materialUID)
 	if queryResult.Error != nil {
 		return Error(c, queryResult.Error)
@@ -1572,39 +1706,67 @@ func (s *FarmServer) UpdateMaterial(c echo.Context) error {
 			materialRead.Type = mt
 		}
 	case strings.ToLower(domain.MaterialTypePlantCode):
-		if plantType != "" {
+		if plantType != "" || packaging != "" {
+			// The one not given is kept from the current type
+			current, _ := materialRead.Type.(domain.MaterialTypePlant)
+			if plantType == "" {
+				plantType = current.PlantType.Code
+			}
+			if packaging == "" {
+				packaging = string(current.Packaging)
+			}
+
 			pt := domain.GetPlantType(plantType)
 			if pt == (domain.PlantType{}) {
 				return Error(c, NewRequestValidationError(INVALID_OPTION, "plant_type"))
 			}
 
-			mt, err = domain.CreateMaterialTypePlant(pt.Code)
+			mt, err = domain.CreateMaterialTypePlantWithPackaging(pt.Code, packaging)
 			if err != nil {
-				return Error(c, NewRequestValidationError(INVALID_OPTION, "type"))
+				return Error(c, NewRequestValidationError(INVALID_OPTION, "packaging"))
 			}
 
 			materialRead.Type = mt
 		}
 	}
 
-	eventQueryResult := <-s.MaterialEventQuery.FindAllByID(materialRead.UID)
-	if eventQueryResult.Error != nil {
-		return Error(c, eventQueryResult.Error)
+	materialResult := <-s.MaterialRepo.FindByID(materialRead.UID)
+	if materialResult.Error != nil {
+		return Error(c, materialResult.Error)
 	}
 
-	events := eventQueryResult.Result.([]storage.MaterialEvent)
-	material := repository.NewMaterialFromHistory(events)
+	material, ok := materialResult.Result.(*domain.Material)
+	if !ok {
+		return Error(c, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error"))
+	}
+
+	changedAt := domain.MaterialClock.Now()
+	changedBy, _ := c.Get("USER_UID").(uuid.UUID)
 
 	if name != "" {
-		material.ChangeName(name)
+		err := material.ChangeName(name, changedAt, changedBy)
+		if err != nil {
+			return Error(c, err)
+		}
 	}
 
 	if mt != nil {
-		material.ChangeType(mt)
+		qu := quantityUnit
+		if qu == "" {
+			qu = material.Quantity.Unit.Code
+		}
+
+		err := material.ChangeType(mt, qu, changedAt, changedBy)
+		if err != nil {
+			return Error(c, err)
+		}
 	}
 
 	if pricePerUnit != "" && currencyCode != "" {
-		material.ChangePricePerUnit(pricePerUnit, currencyCode)
+		err := material.ChangePricePerUnit(pricePerUnit, currencyCode, changedAt, changedBy)
+		if err != nil {
+			return Error(c, err)
+		}
 	}
 
 	if quantity != "" && quantityUnit != "" {
@@ -1613,30 +1775,47 @@ func (s *FarmServer) UpdateMaterial(c echo.Context) error {
 			return Error(c, err)
 		}
 
-		material.ChangeQuantityUnit(float32(q), quantityUnit, materialRead.Type)
+		err = material.ChangeQuantityUnit(float32(q), quantityUnit, materialRead.Type, changedAt, changedBy)
+		if err != nil {
+			return Error(c, err)
+		}
 	}
 
 	if expDate != nil {
-		material.ChangeExpirationDate(*expDate)
+		err := material.ChangeExpirationDate(expDate, changedAt, changedBy)
+		if err != nil {
+			return Error(c, err)
+		}
 	}
 
 	if n != nil {
-		material.ChangeNotes(*n)
+		err := material.ChangeNotes(n, changedAt, changedBy)
+		if err != nil {
+			return Error(c, err)
+		}
 	}
 
 	if pb != nil {
-		material.ChangeProducedBy(*pb)
+		err = material.ChangeProducedBy(*pb, changedAt, changedBy)
+		if err != nil {
+			return Error(c, err)
+		}
 	}
 
-	// Persist //
-	err = <-s.MaterialEventRepo.Save(material.UID, material.Version, material.UncommittedChanges)
+	if sid != nil {
+		err := material.ChangeSupplier(sid, changedAt, changedBy)
+		if err != nil {
+			return Error(c, err)
+		}
+	}
+
+	// Persist and Publish //
+	// Saving fails with ErrConcurrentModification when the material changed since it was loaded.
+	err = s.MaterialService.SaveMaterial(material)
 	if err != nil {
 		return Error(c, err)
 	}
 
-	// Publish //
-	s.publishUncommittedEvents(material)
-
 	data["data"] = MapToMaterial(*material)
 
 	return c.JSON(http.StatusOK, data)
@@ -1672,7 +1851,7 @@ func (s *FarmServer) GetAvailableMaterialPlantType(c echo.Context) error {
 
 	// Process //
 	// TODO: Refactor this query to only get material by plant type
-	result := <-s.MaterialReadQuery.FindAll(params, "", 0, 100)
+	result := <-s.MaterialReadQuery.FindAll(params, "", false, 0, 100)
 
 	materials, ok := result.Result.([]storage.MaterialRead)
 	if !ok {
@@ -1706,6 +1885,8 @@ func (s *FarmServer) publishUncommittedEvents(entity interface{}) error {
 			name := structhelper.GetName(v)
 			s.EventBus.Publish(name, v)
 		}
+
+		e.MarkChangesCommitted()
 	}
 
 	return nil
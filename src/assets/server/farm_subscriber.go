@@ -441,6 +441,7 @@ func (s *FarmServer) SaveToMaterialReadModel(event interface{}) error {
 		materialRead.ExpirationDate = e.ExpirationDate
 		materialRead.Notes = e.Notes
 		materialRead.ProducedBy = e.ProducedBy
+		materialRead.IsExpense = e.IsExpense
 		materialRead.CreatedDate = e.CreatedDate
 
 	case domain.MaterialNameChanged:
@@ -491,6 +492,86 @@ func (s *FarmServer) SaveToMaterialReadModel(event interface{}) error {
 			Value: e.Quantity.Value,
 		}
 
+	case domain.MaterialQuantityConsumed:
+		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
+		if queryResult.Error != nil {
+			log.Error(queryResult.Error)
+		}
+
+		material, ok := queryResult.Result.(storage.MaterialRead)
+		if !ok {
+			log.Error(errors.New("Internal server error. Error type assertion"))
+		}
+
+		materialRead = &material
+
+		materialRead.Quantity.Value -= e.Amount
+
+	case domain.MaterialQuantityRestocked:
+		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
+		if queryResult.Error != nil {
+			log.Error(queryResult.Error)
+		}
+
+		material, ok := queryResult.Result.(storage.MaterialRead)
+		if !ok {
+			log.Error(errors.New("Internal server error. Error type assertion"))
+		}
+
+		materialRead = &material
+
+		materialRead.Quantity.Value += e.Amount
+
+		if e.ExpirationDate != nil {
+			materialRead.ExpirationDate = e.ExpirationDate
+		}
+
+	case domain.MaterialMerged:
+		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
+		if queryResult.Error != nil {
+			log.Error(queryResult.Error)
+		}
+
+		material, ok := queryResult.Result.(storage.MaterialRead)
+		if !ok {
+			log.Error(errors.New("Internal server error. Error type assertion"))
+		}
+
+		materialRead = &material
+
+		materialRead.Quantity.Value += e.Amount
+		materialRead.ExpirationDate = e.ExpirationDate
+
+	case domain.MaterialLotAdded:
+		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
+		if queryResult.Error != nil {
+			log.Error(queryResult.Error)
+		}
+
+		material, ok := queryResult.Result.(storage.MaterialRead)
+		if !ok {
+			log.Error(errors.New("Internal server error. Error type assertion"))
+		}
+
+		materialRead = &material
+
+		materialRead.Quantity.Value += e.Lot.Quantity
+
+	case domain.MaterialLotConsumed:
+		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
+		if queryResult.Error != nil {
+			log.Error(queryResult.Error)
+		}
+
+		material, ok := queryResult.Result.(storage.MaterialRead)
+		if !ok {
+			log.Error(errors.New("Internal server error. Error type assertion"))
+		}
+
+		materialRead = &material
+
+		materialRead.Quantity.Value -= e.Amount
+
 	case domain.MaterialTypeChanged:
 		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
 		if queryResult.Error != nil {
@@ -506,6 +587,30 @@ func (s *FarmServer) SaveToMaterialReadModel(event interface{}) error {
 
 		materialRead.Type = e.MaterialType
 
+		if e.QuantityUnit != (domain.MaterialQuantityUnit{}) {
+			materialRead.Quantity.Unit = e.QuantityUnit
+		}
+
+	case domain.MaterialPlantTypeChanged:
+		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
+		if queryResult.Error != nil {
+			log.Error(queryResult.Error)
+		}
+
+		material, ok := queryResult.Result.(storage.MaterialRead)
+		if !ok {
+			log.Error(errors.New("Internal server error. Error type assertion"))
+		}
+
+		materialRead = &material
+
+		packaging := domain.PlantPackagingUnspecified
+		if mt, ok := materialRead.Type.(domain.MaterialTypePlant); ok {
+			packaging = mt.Packaging
+		}
+
+		materialRead.Type = domain.MaterialTypePlant{PlantType: e.PlantType, Packaging: packaging}
+
 	case domain.MaterialExpirationDateChanged:
 		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
 		if queryResult.Error != nil {
@@ -519,7 +624,7 @@ func (s *FarmServer) SaveToMaterialReadModel(event interface{}) error {
 
 		materialRead = &material
 
-		materialRead.ExpirationDate = &e.ExpirationDate
+		materialRead.ExpirationDate = e.ExpirationDate
 
 	case domain.MaterialNotesChanged:
 		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
@@ -534,7 +639,7 @@ func (s *FarmServer) SaveToMaterialReadModel(event interface{}) error {
 
 		materialRead = &material
 
-		materialRead.Notes = &e.Notes
+		materialRead.Notes = e.Notes
 
 	case domain.MaterialProducedByChanged:
 		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
@@ -550,6 +655,36 @@ func (s *FarmServer) SaveToMaterialReadModel(event interface{}) error {
 		materialRead = &material
 
 		materialRead.ProducedBy = &e.ProducedBy
+
+	case domain.MaterialArchived:
+		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
+		if queryResult.Error != nil {
+			log.Error(queryResult.Error)
+		}
+
+		material, ok := queryResult.Result.(storage.MaterialRead)
+		if !ok {
+			log.Error(errors.New("Internal server error. Error type assertion"))
+		}
+
+		materialRead = &material
+
+		materialRead.Archived = true
+
+	case domain.MaterialUnarchived:
+		queryResult := <-s.MaterialReadQuery.FindByID(e.MaterialUID)
+		if queryResult.Error != nil {
+			log.Error(queryResult.Error)
+		}
+
+		material, ok := queryResult.Result.(storage.MaterialRead)
+		if !ok {
+			log.Error(errors.New("Internal server error. Error type assertion"))
+		}
+
+		materialRead = &material
+
+		materialRead.Archived = false
 	}
 
 	err := <-s.MaterialReadRepo.Save(materialRead)
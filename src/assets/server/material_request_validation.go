@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// MaterialRequest holds the fields of a material create or update request.
+// They are all strings, like the form values the same request can be sent as.
+type MaterialRequest struct {
+	Name           string `json:"name"`
+	PlantType      string `json:"plant_type"`
+	Packaging      string `json:"packaging"`
+	ChemicalType   string `json:"chemical_type"`
+	ContainerType  string `json:"container_type"`
+	PricePerUnit   string `json:"price_per_unit"`
+	CurrencyCode   string `json:"currency_code"`
+	Quantity       string `json:"quantity"`
+	QuantityUnit   string `json:"quantity_unit"`
+	ExpirationDate string `json:"expiration_date"`
+	Notes          string `json:"notes"`
+	ProducedBy     string `json:"produced_by"`
+	IsExpense      string `json:"is_expense"`
+	SupplierID     string `json:"supplier_id"`
+	ExternalID     string `json:"external_id"`
+}
+
+// ParseMaterialRequest reads the material fields from a JSON body,
+// or from the form values for any other content type.
+func ParseMaterialRequest(c echo.Context) (MaterialRequest, error) {
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return DecodeMaterialRequest(c.Request().Body)
+	}
+
+	return MaterialRequest{
+		Name:           c.FormValue("name"),
+		PlantType:      c.FormValue("plant_type"),
+		Packaging:      c.FormValue("packaging"),
+		ChemicalType:   c.FormValue("chemical_type"),
+		ContainerType:  c.FormValue("container_type"),
+		PricePerUnit:   c.FormValue("price_per_unit"),
+		CurrencyCode:   c.FormValue("currency_code"),
+		Quantity:       c.FormValue("quantity"),
+		QuantityUnit:   c.FormValue("quantity_unit"),
+		ExpirationDate: c.FormValue("expiration_date"),
+		Notes:          c.FormValue("notes"),
+		ProducedBy:     c.FormValue("produced_by"),
+		IsExpense:      c.FormValue("is_expense"),
+		SupplierID:     c.FormValue("supplier_id"),
+		ExternalID:     c.FormValue("external_id"),
+	}, nil
+}
+
+// DecodeMaterialRequest decodes a JSON material request and rejects it when it has
+// a field MaterialRequest doesn't know, e.g. a misspelled "expiry_date",
+// instead of silently dropping its value.
+func DecodeMaterialRequest(r io.Reader) (MaterialRequest, error) {
+	req := MaterialRequest{}
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(&req)
+	if err != nil {
+		if field := strings.TrimPrefix(err.Error(), "json: unknown field "); field != err.Error() {
+			return MaterialRequest{}, NewRequestValidationError(UNKNOWN_FIELD, strings.Trim(field, `"`))
+		}
+
+		if te, ok := err.(*json.UnmarshalTypeError); ok {
+			return MaterialRequest{}, NewRequestValidationError(PARSE_FAILED, te.Field)
+		}
+
+		return MaterialRequest{}, NewRequestValidationError(PARSE_FAILED, "body")
+	}
+
+	return req, nil
+}
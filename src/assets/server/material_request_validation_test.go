@@ -0,0 +1,21 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeMaterialRequest(t *testing.T) {
+	// When
+	req, err1 := DecodeMaterialRequest(strings.NewReader(`{"name": "Bayam Lu Hsieh", "quantity": "20", "expiration_date": "2026-12-31"}`))
+	_, err2 := DecodeMaterialRequest(strings.NewReader(`{"name": "Bayam Lu Hsieh", "expiry_date": "2026-12-31"}`))
+	_, err3 := DecodeMaterialRequest(strings.NewReader(`{"name": "Bayam Lu Hsieh", "quantity": 20}`))
+
+	// Then
+	assert.Nil(t, err1)
+	assert.Equal(t, MaterialRequest{Name: "Bayam Lu Hsieh", Quantity: "20", ExpirationDate: "2026-12-31"}, req)
+	assert.Equal(t, NewRequestValidationError(UNKNOWN_FIELD, "expiry_date"), err2)
+	assert.Equal(t, NewRequestValidationError(PARSE_FAILED, "quantity"), err3)
+}
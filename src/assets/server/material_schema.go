@@ -0,0 +1,152 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+)
+
+// materialTypeFields are the MaterialRequest fields that only some material types read,
+// with the types that read them.
+var materialTypeFields = map[string][]string{
+	"plant_type":     {domain.MaterialTypeSeedCode, domain.MaterialTypePlantCode},
+	"chemical_type":  {domain.MaterialTypeAgrochemicalCode},
+	"container_type": {domain.MaterialTypeSeedingContainerCode},
+	"packaging":      {domain.MaterialTypePlantCode},
+}
+
+// MaterialCreateSchemas returns a JSON Schema of the create material request body
+// for every material type, keyed by the :type path param the request is sent to.
+// The properties are the json fields of MaterialRequest, so the schemas
+// accept exactly what DecodeMaterialRequest does. All their values are strings.
+func MaterialCreateSchemas() map[string]interface{} {
+	schemas := make(map[string]interface{})
+
+	for _, mt := range domain.ListMaterialTypes() {
+		schemas[strings.ToLower(mt.Code)] = materialCreateSchema(mt.Code)
+	}
+
+	return schemas
+}
+
+func materialCreateSchema(materialTypeCode string) map[string]interface{} {
+	required := []string{"name", "price_per_unit", "currency_code", "quantity", "quantity_unit"}
+	properties := make(map[string]interface{})
+
+	t := reflect.TypeOf(MaterialRequest{})
+	for i := 0; i < t.NumField(); i++ {
+		field := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+
+		if types, ok := materialTypeFields[field]; ok {
+			if !containsString(types, materialTypeCode) {
+				continue
+			}
+
+			required = append(required, field)
+		}
+
+		property := map[string]interface{}{"type": "string"}
+		for k, v := range materialFieldConstraints(field, materialTypeCode) {
+			property[k] = v
+		}
+
+		properties[field] = property
+	}
+
+	schema := map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                "Material",
+		"type":                 "object",
+		"required":             required,
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+
+	// A plant is counted in the quantity unit of its packaging
+	if materialTypeCode == domain.MaterialTypePlantCode {
+		rules := []interface{}{}
+		for _, v := range []domain.PlantPackaging{domain.PlantPackagingSeedPackets, domain.PlantPackagingIndividual} {
+			rules = append(rules, map[string]interface{}{
+				"if": map[string]interface{}{
+					"properties": map[string]interface{}{"packaging": map[string]interface{}{"const": string(v)}},
+				},
+				"then": map[string]interface{}{
+					"properties": map[string]interface{}{"quantity_unit": map[string]interface{}{"const": v.QuantityUnit()}},
+				},
+			})
+		}
+
+		schema["allOf"] = rules
+	}
+
+	return schema
+}
+
+// materialFieldConstraints are the schema keywords narrowing the string value of a
+// MaterialRequest field, on top of the ones every field has.
+func materialFieldConstraints(field, materialTypeCode string) map[string]interface{} {
+	switch field {
+	case "name":
+		return map[string]interface{}{"minLength": 1}
+	case "price_per_unit", "quantity":
+		return map[string]interface{}{"pattern": `^\d+(\.\d+)?$`}
+	case "currency_code":
+		return map[string]interface{}{"enum": []string{domain.MoneyEUR, domain.MoneyIDR, domain.MoneyUSD}}
+	case "quantity_unit":
+		units := []string{}
+		for _, v := range domain.MaterialQuantityUnits(materialTypeCode) {
+			units = append(units, v.Code)
+		}
+
+		return map[string]interface{}{"enum": units}
+	case "expiration_date":
+		return map[string]interface{}{"format": "date"}
+	case "produced_by":
+		sources := []string{}
+		for _, v := range domain.ProducedBySources() {
+			sources = append(sources, v.Code)
+		}
+
+		return map[string]interface{}{"enum": sources}
+	case "is_expense":
+		return map[string]interface{}{"enum": []string{"true", "false"}}
+	case "supplier_id":
+		return map[string]interface{}{"format": "uuid"}
+	case "plant_type":
+		codes := []string{}
+		for _, v := range domain.PlantTypes() {
+			codes = append(codes, v.Code)
+		}
+
+		return map[string]interface{}{"enum": codes}
+	case "chemical_type":
+		codes := []string{}
+		for _, v := range domain.ChemicalTypes() {
+			codes = append(codes, v.Code)
+		}
+
+		return map[string]interface{}{"enum": codes}
+	case "packaging":
+		return map[string]interface{}{"enum": []string{string(domain.PlantPackagingSeedPackets), string(domain.PlantPackagingIndividual)}}
+	case "container_type":
+		codes := []string{}
+		for _, v := range domain.ContainerTypes() {
+			codes = append(codes, v.Code)
+		}
+
+		return map[string]interface{}{"enum": codes}
+	}
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/domain/service"
+	queryInMem "github.com/Tanibox/tania-core/src/assets/query/inmemory"
+	repoInMem "github.com/Tanibox/tania-core/src/assets/repository/inmemory"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	"github.com/Tanibox/tania-core/src/eventbus"
+	"github.com/asaskevich/EventBus"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+type materialSchemaConst struct {
+	Properties map[string]struct {
+		Const string `json:"const"`
+	} `json:"properties"`
+}
+
+type materialSchema struct {
+	Required   []string `json:"required"`
+	Properties map[string]struct {
+		Type    string   `json:"type"`
+		Enum    []string `json:"enum"`
+		Pattern string   `json:"pattern"`
+	} `json:"properties"`
+	AllOf []struct {
+		If   materialSchemaConst `json:"if"`
+		Then materialSchemaConst `json:"then"`
+	} `json:"allOf"`
+}
+
+func newMaterialTestServer() *FarmServer {
+	materialEventStorage := storage.CreateMaterialEventStorage()
+	materialReadStorage := storage.CreateMaterialReadStorage()
+	materialRepo := repoInMem.NewMaterialRepositoryInMemory(materialEventStorage)
+	materialSubscribers := &service.SubscriberRegistry{}
+
+	return &FarmServer{
+		MaterialEventRepo:   repoInMem.NewMaterialEventRepositoryInMemory(materialEventStorage),
+		MaterialRepo:        materialRepo,
+		MaterialReadRepo:    repoInMem.NewMaterialReadRepositoryInMemory(materialReadStorage),
+		MaterialReadQuery:   queryInMem.NewMaterialReadQueryInMemory(materialReadStorage),
+		MaterialService:     service.MaterialService{MaterialRepo: materialRepo, EventPublisher: materialSubscribers},
+		MaterialSubscribers: materialSubscribers,
+		EventBus:            eventbus.NewSimpleEventBus(EventBus.New()),
+	}
+}
+
+// schemaExample builds a request body with every property of the schema,
+// using the first enum value where there is one and the values the allOf rules
+// require with it.
+func schemaExample(schema materialSchema) map[string]string {
+	example := map[string]string{
+		"name":            "Bayam Lu Hsieh",
+		"price_per_unit":  "10000.50",
+		"quantity":        "20",
+		"expiration_date": "2099-12-31",
+		"notes":           "Bought from the Saturday market",
+		"supplier_id":     "1f3d5e2c-8a4b-4c2d-9e6f-7a8b9c0d1e2f",
+		"external_id":     "seed-import-001",
+	}
+
+	for k, v := range schema.Properties {
+		if len(v.Enum) > 0 {
+			example[k] = v.Enum[0]
+		}
+	}
+
+	for _, rule := range schema.AllOf {
+		matches := true
+		for k, v := range rule.If.Properties {
+			matches = matches && example[k] == v.Const
+		}
+
+		if matches {
+			for k, v := range rule.Then.Properties {
+				example[k] = v.Const
+			}
+		}
+	}
+
+	return example
+}
+
+func TestMaterialCreateSchemas(t *testing.T) {
+	// Given
+	data, err := json.Marshal(MaterialCreateSchemas())
+	assert.Nil(t, err)
+
+	schemas := map[string]materialSchema{}
+	assert.Nil(t, json.Unmarshal(data, &schemas))
+
+	for _, mt := range domain.ListMaterialTypes() {
+		typeParam := strings.ToLower(mt.Code)
+		schema, ok := schemas[typeParam]
+		assert.True(t, ok, typeParam)
+
+		example := schemaExample(schema)
+		for k := range example {
+			if _, ok := schema.Properties[k]; !ok {
+				delete(example, k)
+			}
+		}
+
+		// Then the example is valid against the schema
+		for _, v := range schema.Required {
+			assert.Contains(t, example, v, typeParam)
+		}
+
+		for k, v := range example {
+			property := schema.Properties[k]
+			assert.Equal(t, "string", property.Type, k)
+
+			if property.Pattern != "" {
+				assert.Regexp(t, regexp.MustCompile(property.Pattern), v, k)
+			}
+		}
+
+		// When
+		body, _ := json.Marshal(example)
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(string(body)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("type")
+		c.SetParamValues(typeParam)
+
+		err := newMaterialTestServer().SaveMaterial(c)
+
+		// Then
+		assert.Nil(t, err, typeParam)
+		assert.Equal(t, http.StatusOK, rec.Code, typeParam+": "+rec.Body.String())
+	}
+}
+
+func TestSaveMaterialPlantPackaging(t *testing.T) {
+	var tests = []struct {
+		packaging    string
+		quantityUnit string
+		expected     int
+	}{
+		{string(domain.PlantPackagingSeedPackets), domain.MaterialUnitPackets, http.StatusOK},
+		{string(domain.PlantPackagingIndividual), domain.MaterialUnitUnits, http.StatusOK},
+		{string(domain.PlantPackagingIndividual), domain.MaterialUnitPackets, http.StatusBadRequest},
+		{"BOXES", domain.MaterialUnitUnits, http.StatusBadRequest},
+		{"", domain.MaterialUnitUnits, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		// Given
+		body, _ := json.Marshal(map[string]string{
+			"name":           "Chili Seedling",
+			"plant_type":     domain.PlantTypeVegetable,
+			"packaging":      test.packaging,
+			"price_per_unit": "1",
+			"currency_code":  domain.MoneyEUR,
+			"quantity":       "40",
+			"quantity_unit":  test.quantityUnit,
+		})
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(string(body)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("type")
+		c.SetParamValues(strings.ToLower(domain.MaterialTypePlantCode))
+
+		// When
+		err := newMaterialTestServer().SaveMaterial(c)
+
+		// Then
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, rec.Code, test.packaging+" "+test.quantityUnit+": "+rec.Body.String())
+	}
+}
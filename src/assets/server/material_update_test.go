@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/Tanibox/tania-core/src/assets/storage"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateMaterialRejectsInvalidQuantity(t *testing.T) {
+	var tests = []struct {
+		quantity     string
+		quantityUnit string
+		expected     int
+	}{
+		{"2.5", domain.MaterialUnitPackets, http.StatusBadRequest},
+		{"3", domain.MaterialUnitPackets, http.StatusInternalServerError},
+		{"12", domain.MaterialUnitPackets, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		// Given
+		s := newMaterialTestServer()
+
+		mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+		material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+		material.Reserve(5)
+		assert.Nil(t, s.MaterialService.SaveMaterial(material))
+		assert.Nil(t, <-s.MaterialReadRepo.Save(&storage.MaterialRead{UID: material.UID, Type: mts}))
+
+		body, _ := json.Marshal(map[string]string{
+			"quantity":      test.quantity,
+			"quantity_unit": test.quantityUnit,
+		})
+		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(string(body)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("type", "id")
+		c.SetParamValues(strings.ToLower(domain.MaterialTypeSeedCode), material.UID.String())
+
+		// When
+		err := s.UpdateMaterial(c)
+
+		// Then
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, rec.Code, test.quantity+": "+rec.Body.String())
+
+		result := <-s.MaterialRepo.FindByID(material.UID)
+		saved := result.Result.(*domain.Material)
+		if test.expected == http.StatusOK {
+			assert.Equal(t, float32(12), saved.Quantity.Value)
+		} else {
+			assert.Equal(t, float32(20), saved.Quantity.Value)
+		}
+	}
+}
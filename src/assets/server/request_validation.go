@@ -21,6 +21,7 @@ const (
 	PARSE_FAILED   = "PARSE_FAILED"
 	INVALID_OPTION = "INVALID_OPTION"
 	NOT_FOUND      = "NOT_FOUND"
+	UNKNOWN_FIELD  = "UNKNOWN_FIELD"
 )
 
 // RequestValidation sanitizes request inputs and convert the input to its correct data type.
@@ -66,6 +67,8 @@ func Message(errorCode string) string {
 		return "This value is not available in options. Please give the correct options."
 	case NOT_FOUND:
 		return "Data not found."
+	case UNKNOWN_FIELD:
+		return "This field is unknown. Please check its spelling."
 	default:
 		return "Internal server error"
 	}
@@ -120,6 +123,17 @@ func Error(c echo.Context, err error) error {
 
 		logData.WithField("error_message", re.Error()).Info()
 
+		return c.JSON(http.StatusBadRequest, errorResponse)
+	} else if me, ok := err.(domain.MaterialError); ok {
+		errorResponse["field_name"] = me.Field
+		errorResponse["error_code"] = strconv.Itoa(me.Code)
+		errorResponse["error_message"] = me.Error()
+
+		logData.WithFields(log.Fields{
+			"error_message": me.Error(),
+			"field_name":    me.Field,
+		}).Info()
+
 		return c.JSON(http.StatusBadRequest, errorResponse)
 	} else if rve, ok := err.(RequestValidationError); ok {
 		errorResponse["field_name"] = rve.FieldName
@@ -82,8 +82,8 @@ type Material struct {
 	Type           MaterialType     `json:"type"`
 	Quantity       MaterialQuantity `json:"quantity"`
 	ExpirationDate *time.Time       `json:"expiration_date,omitempty"`
-	Notes          *string          `json:"notes"`
-	ProducedBy     *string          `json:"produced_by"`
+	Notes          *string          `json:"notes,omitempty"`
+	ProducedBy     *string          `json:"produced_by,omitempty"`
 	CreatedDate    time.Time        `json:"created_date"`
 }
 
@@ -109,6 +109,7 @@ type MaterialTypeSeed struct {
 
 type MaterialTypePlant struct {
 	PlantType domain.PlantType `json:"plant_type"`
+	Packaging string           `json:"packaging"`
 }
 
 type MaterialTypeAgrochemical struct {
@@ -468,6 +469,7 @@ func MapToMaterial(material domain.Material) Material {
 			Code: v.Code(),
 			MaterialTypeDetail: MaterialTypePlant{
 				PlantType: v.PlantType,
+				Packaging: string(v.Packaging),
 			},
 		}
 	case domain.MaterialTypeAgrochemical:
@@ -546,6 +548,7 @@ func MapToMaterialFromRead(material storage.MaterialRead) Material {
 			Code: v.Code(),
 			MaterialTypeDetail: MaterialTypePlant{
 				PlantType: v.PlantType,
+				Packaging: string(v.Packaging),
 			},
 		}
 	case domain.MaterialTypeAgrochemical:
@@ -615,6 +618,7 @@ func MapToMaterialSimpleFromRead(materials []storage.MaterialRead) []MaterialSim
 				Code: v.Code(),
 				MaterialTypeDetail: MaterialTypePlant{
 					PlantType: v.PlantType,
+					Packaging: string(v.Packaging),
 				},
 			}
 		case domain.MaterialTypeAgrochemical:
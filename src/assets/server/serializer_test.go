@@ -0,0 +1,28 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Tanibox/tania-core/src/assets/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapToMaterial(t *testing.T) {
+	// Given
+	mts, _ := domain.CreateMaterialTypeSeed(domain.PlantTypeVegetable)
+	material, _ := domain.CreateMaterial("Bayam Lu Hsieh", "12.5", domain.MoneyEUR, mts, 20, domain.MaterialUnitPackets, nil, nil, nil, nil, nil, nil)
+
+	// When
+	data, err := json.Marshal(MapToMaterial(*material))
+
+	// Then
+	assert.Nil(t, err)
+
+	fields := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(data, &fields))
+	assert.NotContains(t, fields, "Version")
+	assert.NotContains(t, fields, "UncommittedChanges")
+	assert.Equal(t, map[string]interface{}{"code": domain.MoneyEUR, "symbol": "€", "amount": "12.50"}, fields["price_per_unit"])
+	assert.Equal(t, map[string]interface{}{"value": float64(20), "unit": domain.MaterialUnitPackets}, fields["quantity"])
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Tanibox/tania-core/src/assets/domain"
 	deadlock "github.com/sasha-s/go-deadlock"
 	uuid "github.com/satori/go.uuid"
 )
@@ -99,8 +100,9 @@ func CreateAreaReadStorage() *AreaReadStorage {
 }
 
 type MaterialEventStorage struct {
-	Lock           *deadlock.RWMutex
-	MaterialEvents []MaterialEvent
+	Lock              *deadlock.RWMutex
+	MaterialEvents    []MaterialEvent
+	MaterialSnapshots map[uuid.UUID]domain.MaterialSnapshot
 }
 
 func CreateMaterialEventStorage() *MaterialEventStorage {
@@ -110,7 +112,10 @@ func CreateMaterialEventStorage() *MaterialEventStorage {
 		fmt.Println("MATERIAL EVENT STORAGE DEADLOCK!")
 	}
 
-	return &MaterialEventStorage{Lock: &rwMutex}
+	return &MaterialEventStorage{
+		Lock:              &rwMutex,
+		MaterialSnapshots: make(map[uuid.UUID]domain.MaterialSnapshot),
+	}
 }
 
 type MaterialReadStorage struct {
@@ -109,11 +109,12 @@ type MaterialRead struct {
 	PricePerUnit   PricePerUnit     `json:"price_per_unit"`
 	Type           MaterialType     `json:"type"`
 	Quantity       MaterialQuantity `json:"quantity"`
-	ExpirationDate *time.Time       `json:"expiration_date"`
-	Notes          *string          `json:"notes"`
-	IsExpense      *bool            `json:"is_expense"`
-	ProducedBy     *string          `json:"produced_by"`
+	ExpirationDate *time.Time       `json:"expiration_date,omitempty"`
+	Notes          *string          `json:"notes,omitempty"`
+	IsExpense      *bool            `json:"is_expense,omitempty"`
+	ProducedBy     *string          `json:"produced_by,omitempty"`
 	CreatedDate    time.Time        `json:"created_date"`
+	Archived       bool             `json:"archived"`
 }
 
 type PricePerUnit domain.PricePerUnit